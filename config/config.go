@@ -0,0 +1,322 @@
+// Package config loads the service configuration. Every setting has a
+// default, and is then overridden in turn by an optional YAML file, the
+// environment (including a .env file, if there is one) and command-line
+// flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MarNawar/carZone/logging"
+	"github.com/MarNawar/carZone/tracing"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Server   Server   `yaml:"server"`
+	Log      Log      `yaml:"log"`
+	Store    Store    `yaml:"store"`
+	Database Database `yaml:"database"`
+	Auth     Auth     `yaml:"auth"`
+	Purge    Purge    `yaml:"purge"`
+}
+
+type Server struct {
+	Port int `yaml:"port"`
+
+	// Deadlines for each route group; imports, exports and purges share
+	// the bulk one
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	BulkTimeout  time.Duration `yaml:"bulk_timeout"`
+
+	// ShutdownTimeout bounds how long in-flight requests may run after
+	// SIGINT or SIGTERM before their connections are closed
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// Addr is the address the HTTP server listens on.
+func (s Server) Addr() string {
+	return fmt.Sprintf(":%d", s.Port)
+}
+
+type Log struct {
+	Level          string `yaml:"level"`
+	TracesExporter string `yaml:"traces_exporter"`
+}
+
+type Store struct {
+	// Backend is postgres, or memory to run without a database
+	Backend  string `yaml:"backend"`
+	SeedData bool   `yaml:"seed_data"`
+}
+
+const (
+	BackendPostgres = "postgres"
+	BackendMemory   = "memory"
+)
+
+type Database struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+
+	// SSLMode is disable, require, verify-ca or verify-full. SSLRootCert is
+	// the CA bundle the server certificate is checked against, and
+	// SSLCert and SSLKey are the client certificate, if the server asks
+	// for one. All three are PEM file paths.
+	SSLMode     string `yaml:"sslmode"`
+	SSLRootCert string `yaml:"sslrootcert"`
+	SSLCert     string `yaml:"sslcert"`
+	SSLKey      string `yaml:"sslkey"`
+
+	// StatementTimeout makes Postgres cancel any statement that runs
+	// longer; 0 turns it off
+	StatementTimeout time.Duration `yaml:"statement_timeout"`
+
+	// Pool limits. 0 means no limit, except for MaxIdleConns where it
+	// means no idle connections are kept
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+
+	// The first connection is retried ConnectRetries times, waiting
+	// ConnectBackoff at first and doubling up to ConnectMaxBackoff
+	ConnectRetries    int           `yaml:"connect_retries"`
+	ConnectBackoff    time.Duration `yaml:"connect_backoff"`
+	ConnectMaxBackoff time.Duration `yaml:"connect_max_backoff"`
+}
+
+type Auth struct {
+	// AdminUsername and AdminPassword bootstrap the first account on an
+	// empty database
+	AdminUsername string `yaml:"admin_username"`
+	AdminPassword string `yaml:"admin_password"`
+
+	// JWTKeysDir holds the PEM signing keys and JWTActiveKID picks the one
+	// used to sign; with no directory an ephemeral key is generated
+	JWTKeysDir   string `yaml:"jwt_keys_dir"`
+	JWTActiveKID string `yaml:"jwt_active_kid"`
+}
+
+type Purge struct {
+	// Soft-deleted cars and engines are kept for Retention, then removed
+	// every Interval (0 turns the background job off)
+	Retention time.Duration `yaml:"retention"`
+	Interval  time.Duration `yaml:"interval"`
+}
+
+// defaultPasswords are refused for the bootstrapped admin account, since
+// the admin is created or promoted with that password on every start.
+var defaultPasswords = map[string]bool{
+	"admin":    true,
+	"admin123": true,
+	"password": true,
+	"changeme": true,
+}
+
+// Default returns the configuration used for anything left unset.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:         8080,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 30 * time.Second,
+			BulkTimeout:  10 * time.Minute,
+
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Log: Log{
+			Level:          "info",
+			TracesExporter: tracing.ExporterNone,
+		},
+		Store: Store{
+			Backend: BackendPostgres,
+		},
+		Database: Database{
+			Host:    "localhost",
+			Port:    5432,
+			SSLMode: "disable",
+			// As long as the bulk timeout, since an export streams a single
+			// SELECT for as long as the client keeps reading
+			StatementTimeout: 10 * time.Minute,
+
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+
+			ConnectRetries:    8,
+			ConnectBackoff:    500 * time.Millisecond,
+			ConnectMaxBackoff: 30 * time.Second,
+		},
+		Purge: Purge{
+			Retention: 30 * 24 * time.Hour,
+			Interval:  24 * time.Hour,
+		},
+	}
+}
+
+// Problems lists everything wrong with a configuration, so it can all be
+// fixed in one go.
+type Problems []string
+
+func (p Problems) Error() string {
+	return "invalid configuration: " + strings.Join(p, "; ")
+}
+
+// Load builds the configuration from args (without the program name) and the
+// environment. It returns the arguments left after the flags, such as a
+// migrate command. A missing .env file is not an error.
+func Load(args []string) (*Config, []string, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("failed to load .env: %w", err)
+	}
+
+	// The file has to be read before the environment and flags override it,
+	// so its path is picked out of the flags first
+	path := configPath(args)
+	cfg := Default()
+	if path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// An empty variable, like JWT_KEYS_DIR= in .env, counts as unset
+	var problems Problems
+	for _, s := range settings(&cfg) {
+		raw := strings.TrimSpace(os.Getenv(s.env))
+		if raw == "" {
+			continue
+		}
+		if err := s.value.Set(raw); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", s.env, err))
+		}
+	}
+
+	flags := newFlagSet(&cfg, new(string))
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, nil, problems
+	}
+	return &cfg, flags.Args(), nil
+}
+
+// configPath returns the -config flag, or CONFIG_FILE. Bad flags are left
+// for the second parse in Load to report.
+func configPath(args []string) string {
+	scratch := Default()
+	path := os.Getenv("CONFIG_FILE")
+	flags := newFlagSet(&scratch, &path)
+	flags.SetOutput(io.Discard)
+	_ = flags.Parse(args)
+	return path
+}
+
+func loadFile(path string, cfg *Config) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Unknown keys are most likely typos, so they are rejected
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func newFlagSet(cfg *Config, path *string) *flag.FlagSet {
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flags.StringVar(path, "config", *path, "YAML config file (CONFIG_FILE)")
+	for _, s := range settings(cfg) {
+		if s.flag != "" {
+			flags.Var(s.value, s.flag, s.usage+" ("+s.env+")")
+		}
+	}
+	return flags
+}
+
+func (c *Config) validate() Problems {
+	var problems Problems
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port < 65536, "PORT must be between 1 and 65535")
+	check(c.Server.ReadTimeout >= 0, "READ_REQUEST_TIMEOUT must not be negative")
+	check(c.Server.WriteTimeout >= 0, "WRITE_REQUEST_TIMEOUT must not be negative")
+	check(c.Server.BulkTimeout >= 0, "BULK_REQUEST_TIMEOUT must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+
+	_, err := logging.ParseLevel(c.Log.Level)
+	check(err == nil, "LOG_LEVEL must be debug, info, warn or error")
+	switch c.Log.TracesExporter {
+	case "", tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		check(false, "OTEL_TRACES_EXPORTER must be otlp, stdout or none")
+	}
+
+	switch c.Store.Backend {
+	case BackendMemory:
+	case BackendPostgres:
+		check(c.Database.Host != "", "DB_HOST is required")
+		check(c.Database.Port > 0 && c.Database.Port < 65536, "DB_PORT must be between 1 and 65535")
+		check(c.Database.User != "", "DB_USER is required")
+		check(c.Database.Name != "", "DB_NAME is required")
+		check(c.Database.StatementTimeout >= 0, "DB_STATEMENT_TIMEOUT must not be negative")
+		check(c.Database.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS must not be negative")
+		check(c.Database.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS must not be negative")
+		check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns, "DB_MAX_IDLE_CONNS must not be more than DB_MAX_OPEN_CONNS")
+		check(c.Database.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME must not be negative")
+		check(c.Database.ConnectRetries > 0, "DB_CONNECT_RETRIES must be at least 1")
+		check(c.Database.ConnectBackoff > 0, "DB_CONNECT_BACKOFF must be positive")
+		check(c.Database.ConnectMaxBackoff >= c.Database.ConnectBackoff, "DB_CONNECT_MAX_BACKOFF must not be less than DB_CONNECT_BACKOFF")
+
+		switch c.Database.SSLMode {
+		case "disable":
+			check(c.Database.SSLRootCert == "" && c.Database.SSLCert == "" && c.Database.SSLKey == "", "DB_SSLROOTCERT, DB_SSLCERT and DB_SSLKEY need DB_SSLMODE other than disable")
+		case "require", "verify-ca", "verify-full":
+		default:
+			check(false, "DB_SSLMODE must be disable, require, verify-ca or verify-full")
+		}
+		check((c.Database.SSLCert == "") == (c.Database.SSLKey == ""), "DB_SSLCERT and DB_SSLKEY must be set together")
+		for _, file := range []struct{ env, path string }{
+			{"DB_SSLROOTCERT", c.Database.SSLRootCert},
+			{"DB_SSLCERT", c.Database.SSLCert},
+			{"DB_SSLKEY", c.Database.SSLKey},
+		} {
+			if file.path != "" {
+				_, err := os.Stat(file.path)
+				check(err == nil, "%s: %v", file.env, err)
+			}
+		}
+	default:
+		check(false, "STORE_BACKEND must be postgres or memory")
+	}
+
+	check(c.Auth.AdminUsername == "" || c.Auth.AdminPassword != "", "ADMIN_PASSWORD is required when ADMIN_USERNAME is set")
+	check(c.Auth.AdminUsername == "" || !defaultPasswords[c.Auth.AdminPassword], "ADMIN_PASSWORD must not be a well-known default")
+
+	check(c.Purge.Retention >= 0, "PURGE_RETENTION must not be negative")
+	check(c.Purge.Interval >= 0, "PURGE_INTERVAL must not be negative")
+	return problems
+}
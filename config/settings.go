@@ -0,0 +1,144 @@
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// setting binds a field of Config to its environment variable and, unless
+// it is a secret that should not show up in a process listing, to a flag.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	value interface {
+		String() string
+		Set(string) error
+	}
+}
+
+func settings(c *Config) []setting {
+	return []setting{
+		{"PORT", "port", "HTTP port to listen on", intValue{&c.Server.Port}},
+		{"READ_REQUEST_TIMEOUT", "read-timeout", "deadline for read requests", durationValue{&c.Server.ReadTimeout}},
+		{"WRITE_REQUEST_TIMEOUT", "write-timeout", "deadline for write requests", durationValue{&c.Server.WriteTimeout}},
+		{"BULK_REQUEST_TIMEOUT", "bulk-timeout", "deadline for imports, exports and purges", durationValue{&c.Server.BulkTimeout}},
+		{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "how long to drain in-flight requests on shutdown", durationValue{&c.Server.ShutdownTimeout}},
+
+		{"LOG_LEVEL", "log-level", "debug, info, warn or error", stringValue{&c.Log.Level}},
+		{"OTEL_TRACES_EXPORTER", "traces-exporter", "otlp, stdout or none", stringValue{&c.Log.TracesExporter}},
+
+		{"STORE_BACKEND", "store", "postgres or memory", stringValue{&c.Store.Backend}},
+		{"SEED_DATA", "seed", "seed the database with dummy data on start", boolValue{&c.Store.SeedData}},
+
+		{"DB_HOST", "db-host", "Postgres host", stringValue{&c.Database.Host}},
+		{"DB_PORT", "db-port", "Postgres port", intValue{&c.Database.Port}},
+		{"DB_USER", "db-user", "Postgres user", stringValue{&c.Database.User}},
+		{"DB_PASSWORD", "", "", stringValue{&c.Database.Password}},
+		{"DB_NAME", "db-name", "Postgres database", stringValue{&c.Database.Name}},
+		{"DB_SSLMODE", "db-sslmode", "disable, require, verify-ca or verify-full", stringValue{&c.Database.SSLMode}},
+		{"DB_SSLROOTCERT", "db-sslrootcert", "CA bundle to verify the server certificate with", stringValue{&c.Database.SSLRootCert}},
+		{"DB_SSLCERT", "db-sslcert", "client certificate", stringValue{&c.Database.SSLCert}},
+		{"DB_SSLKEY", "db-sslkey", "client certificate key", stringValue{&c.Database.SSLKey}},
+		{"DB_STATEMENT_TIMEOUT", "db-statement-timeout", "server-side limit on each statement, 0 for none", durationValue{&c.Database.StatementTimeout}},
+		{"DB_MAX_OPEN_CONNS", "db-max-open-conns", "most connections open at once, 0 for no limit", intValue{&c.Database.MaxOpenConns}},
+		{"DB_MAX_IDLE_CONNS", "db-max-idle-conns", "most idle connections kept, 0 for none", intValue{&c.Database.MaxIdleConns}},
+		{"DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "how long a connection is reused, 0 for ever", durationValue{&c.Database.ConnMaxLifetime}},
+		{"DB_CONNECT_RETRIES", "db-connect-retries", "attempts to connect before giving up", intValue{&c.Database.ConnectRetries}},
+		{"DB_CONNECT_BACKOFF", "db-connect-backoff", "wait before the first retry, doubled after each", durationValue{&c.Database.ConnectBackoff}},
+		{"DB_CONNECT_MAX_BACKOFF", "db-connect-max-backoff", "longest wait between retries", durationValue{&c.Database.ConnectMaxBackoff}},
+
+		{"ADMIN_USERNAME", "admin-username", "admin account created on an empty database", stringValue{&c.Auth.AdminUsername}},
+		{"ADMIN_PASSWORD", "", "", stringValue{&c.Auth.AdminPassword}},
+		{"JWT_KEYS_DIR", "jwt-keys-dir", "directory of PEM signing keys", stringValue{&c.Auth.JWTKeysDir}},
+		{"JWT_ACTIVE_KID", "jwt-active-kid", "key ID used to sign tokens", stringValue{&c.Auth.JWTActiveKID}},
+
+		{"PURGE_RETENTION", "purge-retention", "how long soft-deleted records are kept", durationValue{&c.Purge.Retention}},
+		{"PURGE_INTERVAL", "purge-interval", "how often the purge runs, 0 for never", durationValue{&c.Purge.Interval}},
+	}
+}
+
+// The value types implement flag.Value over a field of Config.
+
+type stringValue struct{ p *string }
+
+func (v stringValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return *v.p
+}
+
+func (v stringValue) Set(s string) error {
+	*v.p = s
+	return nil
+}
+
+type intValue struct{ p *int }
+
+func (v intValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return strconv.Itoa(*v.p)
+}
+
+func (v intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return errInvalid(s, "a whole number")
+	}
+	*v.p = n
+	return nil
+}
+
+type boolValue struct{ p *bool }
+
+func (v boolValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return strconv.FormatBool(*v.p)
+}
+
+func (v boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return errInvalid(s, "true or false")
+	}
+	*v.p = b
+	return nil
+}
+
+// IsBoolFlag lets the flag be given as -seed without a value.
+func (v boolValue) IsBoolFlag() bool { return true }
+
+type durationValue struct{ p *time.Duration }
+
+func (v durationValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return v.p.String()
+}
+
+func (v durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return errInvalid(s, "a duration such as 30s or 720h")
+	}
+	*v.p = d
+	return nil
+}
+
+func errInvalid(value, expected string) error {
+	return &invalidValueError{value: value, expected: expected}
+}
+
+type invalidValueError struct {
+	value, expected string
+}
+
+func (e *invalidValueError) Error() string {
+	return strconv.Quote(e.value) + " is not " + e.expected
+}
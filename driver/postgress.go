@@ -1,50 +1,127 @@
 package driver
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
 	"time"
+
+	"github.com/MarNawar/carZone/config"
 	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/uptrace/opentelemetry-go-extra/otelsql"
 
 )
 
 var db *sql.DB 
-func InitDB() {
-	connStr := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-	)
-	log.Println(connStr)
 
-	const maxRetries = 5          
-	const retryInterval = 2 * time.Second
+// pingTimeout bounds each connection attempt.
+const pingTimeout = 5 * time.Second
 
+// InitDB opens the connection pool and waits for Postgres to answer,
+// retrying with backoff while the server comes up, and fails if it never
+// does.
+func InitDB(cfg config.Database) error {
+	// Never log the DSN itself, it holds the password
+	slog.Info("Connecting to database",
+		"host", cfg.Host,
+		"port", cfg.Port,
+		"user", cfg.User,
+		"dbname", cfg.Name,
+		"sslmode", cfg.SSLMode,
+		"statement_timeout", cfg.StatementTimeout.String(),
+		"max_open_conns", cfg.MaxOpenConns,
+		"max_idle_conns", cfg.MaxIdleConns,
+		"conn_max_lifetime", cfg.ConnMaxLifetime.String(),
+	)
+
+	// otelsql traces every query as a child of the span in its context.
+	// Opening only validates the DSN, nothing is dialled until the ping.
 	var err error
-	for i := 1; i <= maxRetries; i++ {
-		db, err = sql.Open("postgres", connStr)
-		if err != nil {
-			log.Printf("Attempt %d: Error opening database: %v", i, err)
-		} else if err = db.Ping(); err == nil {
-			fmt.Println("Successfully connected to the database")
-			return
+	db, err = otelsql.Open("postgres", dsn(cfg),
+		otelsql.WithDBSystem("postgresql"),
+		otelsql.WithDBName(cfg.Name),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+		err = db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			break
+		}
+		if attempt >= cfg.ConnectRetries {
+			_ = db.Close()
+			db = nil
+			return fmt.Errorf("failed to connect to the database after %d attempts: %w", attempt, err)
 		}
 
-		log.Printf("Attempt %d: Could not connect to database, retrying in %v...", i, retryInterval)
-		time.Sleep(retryInterval)
+		wait := backoff(attempt, cfg.ConnectBackoff, cfg.ConnectMaxBackoff)
+		slog.Warn("Could not connect to database, retrying", "attempt", attempt, "retry_in", wait.String(), "error", err)
+		time.Sleep(wait)
 	}
 
-	log.Fatalf("Failed to connect to the database after %d attempts: %v", maxRetries, err)
+	slog.Info("Successfully connected to the database")
+	// Pool stats such as open and in-use connections for /metrics
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, cfg.Name))
+	return nil
+}
+
+// dsn builds a postgres:// URL, which escapes a password or path holding
+// spaces or quotes that would break the key=value form. lib/pq passes
+// statement_timeout on to the server as a session setting.
+func dsn(cfg config.Database) string {
+	query := url.Values{}
+	query.Set("sslmode", cfg.SSLMode)
+	if cfg.SSLRootCert != "" {
+		query.Set("sslrootcert", cfg.SSLRootCert)
+	}
+	if cfg.SSLCert != "" {
+		query.Set("sslcert", cfg.SSLCert)
+		query.Set("sslkey", cfg.SSLKey)
+	}
+	query.Set("statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     "/" + cfg.Name,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// backoff is the wait before retrying after the given failed attempt: base
+// doubled per attempt up to max, of which a random half is taken off so
+// replicas restarting together do not retry in lockstep.
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	wait := max
+	// Compared this way round so the shift cannot overflow
+	if shift := attempt - 1; shift < 63 && base <= max>>shift {
+		wait = base << shift
+	}
+	half := wait / 2
+	return half + rand.N(half+1)
 }
 
 func GetDB() *sql.DB {
 	if db == nil {
-		log.Fatal("Database connection is not initialized. Call InitDB first.")
+		slog.Error("Database connection is not initialized. Call InitDB first.")
+		os.Exit(1)
 	}
 	return db
 }
@@ -53,7 +130,7 @@ func CloseDB() {
 	if db != nil {
 		err := db.Close()
 		if err != nil {
-			log.Fatalf("Error closing the Database: %v", err)
+			slog.Error("Error closing the Database", "error", err)
 		}
 	}
 }
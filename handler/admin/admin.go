@@ -0,0 +1,30 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/MarNawar/carZone/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	purgeService service.PurgeServiceInterface
+}
+
+func NewAdminHandler(purgeService service.PurgeServiceInterface) *AdminHandler {
+	return &AdminHandler{
+		purgeService: purgeService,
+	}
+}
+
+// HandlePurge serves POST /admin/purge, running the purge job right away.
+func (h *AdminHandler) HandlePurge(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	res, err := h.purgeService.Purge(ctx)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	service service.AuditServiceInterface
+}
+
+func NewAuditHandler(service service.AuditServiceInterface) *AuditHandler {
+	return &AuditHandler{
+		service: service,
+	}
+}
+
+// HandleListAudit serves GET /audit?entity=car&id=..., newest entries first.
+// Pass the last entry's ID as before= to get the next page.
+func (h *AuditHandler) HandleListAudit(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := models.AuditFilter{
+		Entity:   models.AuditEntity(c.Query("entity")),
+		EntityID: c.Query("id"),
+		Actor:    c.Query("actor"),
+		Limit:    models.DefaultAuditLimit,
+	}
+
+	var err error
+	if raw := c.Query("limit"); raw != "" {
+		if filter.Limit, err = strconv.Atoi(raw); err != nil {
+			_ = c.Error(models.Validation("limit must be a whole number"))
+			return
+		}
+	}
+	if raw := c.Query("before"); raw != "" {
+		if filter.BeforeID, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			_ = c.Error(models.Validation("before must be a whole number"))
+			return
+		}
+	}
+
+	res, err := h.service.ListAudit(ctx, filter)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": res})
+}
@@ -1,7 +1,8 @@
 package car
 
 import (
-	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
@@ -22,36 +23,114 @@ func NewCarHandler(service service.CarServiceInterface) *CarHandler {
 }
 
 func (h *CarHandler) HandleGetCarByID(c *gin.Context) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": true,
-			"message": "please provide the valid id",
-		})
+	if _, err := models.ParseID(id); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
 	res, err := h.service.GetCarById(ctx, id)
 	if err != nil{
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while fetching the car item"})
+		_ = c.Error(err)
 		return
 	}
+	c.Header("ETag", models.ETag(res.Version))
 	c.JSON(http.StatusOK, res)
 }
 
+// HandleListCars serves GET /cars. Requests that still pass isEngine get the
+// original unpaginated brand lookup; everything else gets a page of cars.
+func (h *CarHandler) HandleListCars(c *gin.Context) {
+	if _, legacy := c.GetQuery("isEngine"); legacy {
+		h.HandleGetCarByBrand(c)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	filter, err := carFilterFromQuery(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	res, err := h.service.ListCars(ctx, filter)
+	if err != nil{
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// HandleExportCars serves GET /cars/export?format=csv|ndjson|xlsx with the
+// same filters and sort as GET /cars, streaming every matching car as it is
+// read. Errors before any of the file reaches the client get the usual
+// error response. Once it has started the status is already sent, so the
+// connection is dropped instead and the client sees a broken download
+// rather than a file that looks complete.
+func (h *CarHandler) HandleExportCars(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	format, err := models.ParseExportFormat(c.Query("format"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	filter, err := carFilterFromQuery(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	filter.Limit, filter.Cursor = 0, ""
+
+	// The response starts with the first row, or at the end for an empty export
+	var exporter models.CarExporter
+	start := func() error {
+		filename := fmt.Sprintf("cars-%s.%s", time.Now().UTC().Format("20060102"), format)
+		c.Header("Content-Type", format.ContentType())
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Status(http.StatusOK)
+		exporter, err = models.NewCarExporter(c.Writer, format)
+		return err
+	}
+
+	err = h.service.ExportCars(ctx, filter, func(car models.Car) error {
+		if exporter == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return exporter.Write(car)
+	})
+	if err == nil && exporter == nil {
+		err = start()
+	}
+	if err == nil {
+		err = exporter.Close()
+	}
+	if err != nil {
+		if !c.Writer.Written() {
+			// Nothing has left the buffers yet, so a normal JSON error can
+			// still be sent in place of the file
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			_ = c.Error(err)
+			return
+		}
+		slog.ErrorContext(ctx, "Export of cars aborted", "format", format, "error", err)
+		// net/http closes the connection on this panic without logging it
+		panic(http.ErrAbortHandler)
+	}
+}
+
 func (h *CarHandler) HandleGetCarByBrand(c *gin.Context) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	brand := c.Query("brand")
 	if brand == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": true,
-			"message": "please provide the valid brand",
-		})
+		_ = c.Error(models.Validation("please provide the valid brand"))
 		return
 	}
 
@@ -59,84 +138,248 @@ func (h *CarHandler) HandleGetCarByBrand(c *gin.Context) {
 	isEngine, err := strconv.ParseBool(isEngineStr)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": true,
-			"message": "please provide the valid isEngine",
-		})
+		_ = c.Error(models.Validation("please provide the valid isEngine"))
 		return
 	}
 
 	res, err := h.service.GetCarsByBrand(ctx, brand, isEngine)
 	if err != nil{
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, res)
 }
 
 
+// HandleGetCarsByEngine serves GET /engine/:id/cars.
+func (h *CarHandler) HandleGetCarsByEngine(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id := c.Param("id")
+	if _, err := models.ParseID(id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	res, err := h.service.GetCarsByEngine(ctx, id)
+	if err != nil{
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
 func (h *CarHandler) HandleCreateCar(c *gin.Context){
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
-	var carReq *models.CarRequest
-	if err := c.BindJSON(&carReq); err != nil{
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var carReq models.CarRequest
+	if err := c.ShouldBindJSON(&carReq); err != nil {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
 		return
 	}
 
-	res, err := h.service.CreateCar(ctx, carReq)
+	res, err := h.service.CreateCar(ctx, &carReq)
 	if err != nil{
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
+	c.Header("ETag", models.ETag(res.Version))
 	c.JSON(http.StatusOK, res)
 }
 
+// HandleImportCars serves POST /cars/import with a CSV or NDJSON body, chosen by
+// ?format or the content type. ?mode=best_effort keeps the valid rows when
+// others fail; the default atomic mode then inserts nothing and answers 422.
+func (h *CarHandler) HandleImportCars(c *gin.Context){
+	ctx := c.Request.Context()
+
+	mode, err := models.ParseImportMode(c.Query("mode"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	format, err := models.ImportFormatFor(c.Query("format"), c.GetHeader("Content-Type"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	reader := models.NewImportReader(c.Request.Body, format, models.CarImportColumns)
+	res, err := h.service.ImportCars(ctx, reader, mode)
+	if err != nil{
+		_ = c.Error(err)
+		return
+	}
+
+	status := http.StatusOK
+	if mode == models.ImportAtomic && res.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, res)
+}
+
 func (h *CarHandler) HandleUpdateCar(c *gin.Context){
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
-	var carReq *models.CarRequest
-	if err := c.BindJSON(&carReq); err != nil{
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var carReq models.CarRequest
+	if err := c.ShouldBindJSON(&carReq); err != nil {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
 		return
 	}
 
 	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": true,
-			"message": "please provide the valid id",
-		})
+	if _, err := models.ParseID(id); err != nil {
+		_ = c.Error(err)
 		return
 	}
 	
-	res, err := h.service.UpdateCar(ctx, id, carReq)
+	// If-Match makes the update conditional on the version from a GET
+	expectedVersion, err := models.ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	res, err := h.service.UpdateCar(ctx, id, &carReq, expectedVersion)
+	if err != nil{
+		_ = c.Error(err)
+		return
+	}
+	c.Header("ETag", models.ETag(res.Version))
+	c.JSON(http.StatusOK, res)
+}
+
+// HandlePatchCar serves PATCH /car/:id with an application/merge-patch+json
+// or application/json-patch+json body.
+func (h *CarHandler) HandlePatchCar(c *gin.Context){
+	ctx := c.Request.Context()
+
+	id := c.Param("id")
+	if _, err := models.ParseID(id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
+		return
+	}
+
+	expectedVersion, err := models.ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	res, err := h.service.PatchCar(ctx, id, patch, c.ContentType(), expectedVersion)
 	if err != nil{
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
+	c.Header("ETag", models.ETag(res.Version))
 	c.JSON(http.StatusOK, res)
 }
 
 func (h *CarHandler) HandleDeleteCar(c *gin.Context){
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": true,
-			"message": "please provide the valid id",
-		})
+	if _, err := models.ParseID(id); err != nil {
+		_ = c.Error(err)
 		return
 	}
 	
 	res, err := h.service.DeleteCar(ctx, id)
 	if err != nil{
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, res)
 }
+
+// HandleRestoreCar serves POST /car/:id/restore, undoing a delete.
+func (h *CarHandler) HandleRestoreCar(c *gin.Context){
+	ctx := c.Request.Context()
+
+	id := c.Param("id")
+	if _, err := models.ParseID(id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	res, err := h.service.RestoreCar(ctx, id)
+	if err != nil{
+		_ = c.Error(err)
+		return
+	}
+	c.Header("ETag", models.ETag(res.Version))
+	c.JSON(http.StatusOK, res)
+}
+
+func carFilterFromQuery(c *gin.Context) (models.CarFilter, error) {
+	filter := models.CarFilter{
+		Brand:    c.Query("brand"),
+		Name:     c.Query("name"),
+		FuelType: c.Query("fuel_type"),
+		Cursor:   c.Query("cursor"),
+		Limit:    models.DefaultPageLimit,
+	}
+
+	var err error
+	if filter.Sort, err = models.ParseCarSort(c.Query("sort")); err != nil {
+		return filter, err
+	}
+
+	ints := []struct {
+		param string
+		dest  *int
+	}{
+		{"limit", &filter.Limit},
+		{"year_min", &filter.MinYear},
+		{"year_max", &filter.MaxYear},
+	}
+	for _, p := range ints {
+		if raw := c.Query(p.param); raw != "" {
+			if *p.dest, err = strconv.Atoi(raw); err != nil {
+				return filter, models.Validation("%s must be a whole number", p.param)
+			}
+		}
+	}
+
+	int64s := []struct {
+		param string
+		dest  *int64
+	}{
+		{"displacement_min", &filter.MinDisplacement},
+		{"displacement_max", &filter.MaxDisplacement},
+		{"cylinders_min", &filter.MinCylinders},
+		{"cylinders_max", &filter.MaxCylinders},
+		{"range_min", &filter.MinRange},
+		{"range_max", &filter.MaxRange},
+	}
+	for _, p := range int64s {
+		if raw := c.Query(p.param); raw != "" {
+			if *p.dest, err = strconv.ParseInt(raw, 10, 64); err != nil {
+				return filter, models.Validation("%s must be a whole number", p.param)
+			}
+		}
+	}
+
+	floats := []struct {
+		param string
+		dest  *float64
+	}{
+		{"price_min", &filter.MinPrice},
+		{"price_max", &filter.MaxPrice},
+	}
+	for _, p := range floats {
+		if raw := c.Query(p.param); raw != "" {
+			if *p.dest, err = strconv.ParseFloat(raw, 64); err != nil {
+				return filter, models.Validation("%s must be a number", p.param)
+			}
+		}
+	}
+
+	return filter, nil
+}
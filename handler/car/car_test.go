@@ -0,0 +1,85 @@
+package car
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MarNawar/carZone/middleware"
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// exportService streams rows cars and then fails the export.
+type exportService struct {
+	service.CarServiceInterface
+	rows int
+}
+
+func (s exportService) ExportCars(ctx context.Context, filter models.CarFilter, fn func(models.Car) error) error {
+	for i := 0; i < s.rows; i++ {
+		if err := fn(models.Car{ID: uuid.New(), Name: "Roadster", Brand: "Tesla"}); err != nil {
+			return err
+		}
+	}
+	return errors.New("connection reset by database")
+}
+
+func exportServer(t *testing.T, rows int) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler())
+	router.GET("/cars/export", NewCarHandler(exportService{rows: rows}).HandleExportCars)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHandleExportCarsBreaksTransferOnLateError(t *testing.T) {
+	for _, format := range []string{"csv", "ndjson", "xlsx"} {
+		t.Run(format, func(t *testing.T) {
+			// Enough rows to get past the exporters' buffers onto the wire
+			server := exportServer(t, 1000)
+
+			resp, err := http.Get(server.URL + "/cars/export?format=" + format)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %d, want 200", resp.StatusCode)
+			}
+
+			if _, err := io.ReadAll(resp.Body); !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Fatalf("reading body: err = %v, want %v for a cut-short transfer", err, io.ErrUnexpectedEOF)
+			}
+		})
+	}
+}
+
+func TestHandleExportCarsReportsEarlyError(t *testing.T) {
+	server := exportServer(t, 1)
+
+	resp, err := http.Get(server.URL + "/cars/export?format=csv")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	if cd := resp.Header.Get("Content-Disposition"); cd != "" {
+		t.Errorf("Content-Disposition = %q, want none", cd)
+	}
+}
@@ -1,9 +1,8 @@
 package engine
 
 import (
-	"context"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/MarNawar/carZone/models"
 	"github.com/MarNawar/carZone/service"
@@ -21,89 +20,238 @@ func NewEngineHandler(service service.EngineServiceInterface) *EngineHandler {
 }
 
 func (h *EngineHandler) HandleGetEngineByID(c *gin.Context) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": true,
-			"message": "please provide the valid id",
-		})
+	if _, err := models.ParseID(id); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
 	res, err := h.service.GetEngineByID(ctx, id)
 	if err != nil{
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while fetching the engine"})
+		_ = c.Error(err)
 		return
 	}
+	c.Header("ETag", models.ETag(res.Version))
 	c.JSON(http.StatusOK, res)
 }
 
+// HandleListEngines serves GET /engines with the same cursor pagination as
+// GET /cars.
+func (h *EngineHandler) HandleListEngines(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter, err := engineFilterFromQuery(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	res, err := h.service.ListEngines(ctx, filter)
+	if err != nil{
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
 
 func (h *EngineHandler) HandleCreateEngine(c *gin.Context){
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
-	var engineRequest *models.EngineRequest
-	if err := c.BindJSON(&engineRequest); err != nil{
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var engineRequest models.EngineRequest
+	if err := c.ShouldBindJSON(&engineRequest); err != nil {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
 		return
 	}
 
-	res, err := h.service.CreateEngine(ctx, engineRequest)
+	res, err := h.service.CreateEngine(ctx, &engineRequest)
 	if err != nil{
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while creating the engine"})
+		_ = c.Error(err)
 		return
 	}
+	c.Header("ETag", models.ETag(res.Version))
 	c.JSON(http.StatusOK, res)
 }
 
+// HandleImportEngines serves POST /engines/import with a CSV or NDJSON body, chosen by
+// ?format or the content type. ?mode=best_effort keeps the valid rows when
+// others fail; the default atomic mode then inserts nothing and answers 422.
+func (h *EngineHandler) HandleImportEngines(c *gin.Context){
+	ctx := c.Request.Context()
+
+	mode, err := models.ParseImportMode(c.Query("mode"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	format, err := models.ImportFormatFor(c.Query("format"), c.GetHeader("Content-Type"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	reader := models.NewImportReader(c.Request.Body, format, models.EngineImportColumns)
+	res, err := h.service.ImportEngines(ctx, reader, mode)
+	if err != nil{
+		_ = c.Error(err)
+		return
+	}
+
+	status := http.StatusOK
+	if mode == models.ImportAtomic && res.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, res)
+}
+
 func (h *EngineHandler) HandleUpdateEngine(c *gin.Context){
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
-	var engineRequest *models.EngineRequest
-	if err := c.BindJSON(&engineRequest); err != nil{
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var engineRequest models.EngineRequest
+	if err := c.ShouldBindJSON(&engineRequest); err != nil {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
 		return
 	}
 
 	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": true,
-			"message": "please provide the valid id",
-		})
+	if _, err := models.ParseID(id); err != nil {
+		_ = c.Error(err)
 		return
 	}
 	
-	res, err := h.service.UpdateEngine(ctx, engineRequest, id)
+	// If-Match makes the update conditional on the version from a GET
+	expectedVersion, err := models.ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	res, err := h.service.UpdateEngine(ctx, &engineRequest, id, expectedVersion)
+	if err != nil{
+		_ = c.Error(err)
+		return
+	}
+	c.Header("ETag", models.ETag(res.Version))
+	c.JSON(http.StatusOK, res)
+}
+
+// HandlePatchEngine serves PATCH /engine/:id with an application/merge-patch+json
+// or application/json-patch+json body.
+func (h *EngineHandler) HandlePatchEngine(c *gin.Context){
+	ctx := c.Request.Context()
+
+	id := c.Param("id")
+	if _, err := models.ParseID(id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
+		return
+	}
+
+	expectedVersion, err := models.ParseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	res, err := h.service.PatchEngine(ctx, id, patch, c.ContentType(), expectedVersion)
 	if err != nil{
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while updating the engine"})
+		_ = c.Error(err)
 		return
 	}
+	c.Header("ETag", models.ETag(res.Version))
 	c.JSON(http.StatusOK, res)
 }
 
+// HandleDeleteEngine serves DELETE /engine/:id. It refuses while cars use
+// the engine unless ?cascade=true or ?reassign_to=<engine_id> says what to
+// do with them.
 func (h *EngineHandler) HandleDeleteEngine(c *gin.Context){
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
+	ctx := c.Request.Context()
 
 	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": true,
-			"message": "please provide the valid id",
-		})
+	if _, err := models.ParseID(id); err != nil {
+		_ = c.Error(err)
 		return
 	}
 	
-	res, err := h.service.DeleteEngine(ctx, id)
+	options := models.EngineDeleteOptions{ReassignTo: c.Query("reassign_to")}
+	if raw := c.Query("cascade"); raw != "" {
+		cascade, err := strconv.ParseBool(raw)
+		if err != nil {
+			_ = c.Error(models.Validation("cascade must be true or false"))
+			return
+		}
+		options.Cascade = cascade
+	}
+
+	res, err := h.service.DeleteEngine(ctx, id, options)
 	if err != nil{
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "error while deleting the engine item"})
+		_ = c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, res)
 }
+
+// HandleRestoreEngine serves POST /engine/:id/restore, undoing a delete.
+func (h *EngineHandler) HandleRestoreEngine(c *gin.Context){
+	ctx := c.Request.Context()
+
+	id := c.Param("id")
+	if _, err := models.ParseID(id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	res, err := h.service.RestoreEngine(ctx, id)
+	if err != nil{
+		_ = c.Error(err)
+		return
+	}
+	c.Header("ETag", models.ETag(res.Version))
+	c.JSON(http.StatusOK, res)
+}
+
+func engineFilterFromQuery(c *gin.Context) (models.EngineFilter, error) {
+	filter := models.EngineFilter{
+		Cursor: c.Query("cursor"),
+		Limit:  models.DefaultPageLimit,
+	}
+
+	var err error
+	if filter.Sort, err = models.ParseEngineSort(c.Query("sort")); err != nil {
+		return filter, err
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		if filter.Limit, err = strconv.Atoi(raw); err != nil {
+			return filter, models.Validation("limit must be a whole number")
+		}
+	}
+
+	int64s := []struct {
+		param string
+		dest  *int64
+	}{
+		{"displacement_min", &filter.MinDisplacement},
+		{"displacement_max", &filter.MaxDisplacement},
+		{"cylinders_min", &filter.MinCylinders},
+		{"cylinders_max", &filter.MaxCylinders},
+		{"range_min", &filter.MinRange},
+		{"range_max", &filter.MaxRange},
+	}
+	for _, p := range int64s {
+		if raw := c.Query(p.param); raw != "" {
+			if *p.dest, err = strconv.ParseInt(raw, 10, 64); err != nil {
+				return filter, models.Validation("%s must be a whole number", p.param)
+			}
+		}
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,35 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/MarNawar/carZone/service"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	service service.HealthServiceInterface
+}
+
+func NewHealthHandler(service service.HealthServiceInterface) *HealthHandler {
+	return &HealthHandler{
+		service: service,
+	}
+}
+
+// HandleLiveness serves GET /healthz. It only shows the process is serving
+// requests; dependencies are left to /readyz so a database outage does not
+// get every replica restarted.
+func (h *HealthHandler) HandleLiveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// HandleReadiness serves GET /readyz, answering 503 while any check fails.
+func (h *HealthHandler) HandleReadiness(c *gin.Context) {
+	readiness := h.service.Ready(c.Request.Context())
+	status := http.StatusOK
+	if !readiness.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, readiness)
+}
@@ -1,35 +1,180 @@
 package login
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/MarNawar/carZone/middleware"
 	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/service"
 	"github.com/gin-gonic/gin"
 )
 
-func Login(c *gin.Context) {
-	var user models.User
+type LoginHandler struct {
+	service      service.UserServiceInterface
+	tokenService service.TokenServiceInterface
+	keys         *middleware.KeySet
+}
+
+func NewLoginHandler(service service.UserServiceInterface, tokenService service.TokenServiceInterface, keys *middleware.KeySet) *LoginHandler {
+	return &LoginHandler{
+		service:      service,
+		tokenService: tokenService,
+		keys:         keys,
+	}
+}
+
+func (h *LoginHandler) HandleLogin(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var userReq models.UserRequest
+	if err := c.ShouldBindJSON(&userReq); err != nil {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
+		return
+	}
+
+	user, err := h.service.Login(ctx, &userReq)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	refreshToken, err := h.tokenService.CreateRefreshToken(ctx, user.UserName)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	h.respondWithTokens(c, user, refreshToken)
+}
+
+func (h *LoginHandler) HandleRefresh(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var refreshReq models.RefreshRequest
+	if err := c.ShouldBindJSON(&refreshReq); err != nil {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
+		return
+	}
+
+	user, refreshToken, err := h.tokenService.Refresh(ctx, refreshReq.RefreshToken)
+	if err != nil {
+		// A token for a deleted user is just an invalid token to the client
+		if errors.Is(err, models.ErrUserNotFound) {
+			err = models.ErrInvalidToken
+		}
+		_ = c.Error(err)
+		return
+	}
+
+	h.respondWithTokens(c, user, refreshToken)
+}
+
+func (h *LoginHandler) HandleLogout(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// The refresh token is optional; without it only the access token dies.
+	// An empty body, whatever its Content-Length says, decodes to io.EOF.
+	var refreshReq models.RefreshRequest
+	if err := c.ShouldBindJSON(&refreshReq); err != nil && !errors.Is(err, io.EOF) {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
+		return
+	}
+
+	err := h.tokenService.Revoke(ctx, c.GetString("jti"), c.GetTime("token_expires_at"), refreshReq.RefreshToken)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// HandleJWKS publishes the public signing keys for other services.
+func (h *LoginHandler) HandleJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
+
+func (h *LoginHandler) respondWithTokens(c *gin.Context, user *models.User, refreshToken string) {
+	tokenString, err := h.keys.GenerateToken(user.UserName, user.Role)
+	if err != nil {
+		_ = c.Error(fmt.Errorf("failed to generate token: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TokenResponse{
+		Token:        tokenString,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(middleware.AccessTokenTTL.Seconds()),
+	})
+}
+
+func (h *LoginHandler) HandleRegister(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var userReq models.UserRequest
+	if err := c.ShouldBindJSON(&userReq); err != nil {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
+		return
+	}
+
+	res, err := h.service.Register(ctx, &userReq)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
 
-	if err := c.BindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+func (h *LoginHandler) HandleChangePassword(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var passwordReq models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&passwordReq); err != nil {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
 		return
 	}
 
-	valid := (user.UserName == "admin" && user.Password == "admin123")
+	res, err := h.service.ChangePassword(ctx, c.GetString("username"), &passwordReq)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *LoginHandler) HandleSetRole(c *gin.Context) {
+	ctx := c.Request.Context()
 
-	if !valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "provide valid user name or password"})
+	var roleReq models.UserRoleRequest
+	if err := c.ShouldBindJSON(&roleReq); err != nil {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
+		return
 	}
 
-	tokenString, err := middleware.GenerateToken(user.UserName)
+	res, err := h.service.SetRole(ctx, c.Param("username"), &roleReq)
 	if err != nil {
-		log.Println("Error Generating Token:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to Generate Token"})
+		_ = c.Error(err)
 		return
 	}
+	c.JSON(http.StatusOK, res)
+}
 
-	c.JSON(http.StatusOK, gin.H{"token": tokenString})
+func (h *LoginHandler) HandleSetStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var statusReq models.UserStatusRequest
+	if err := c.ShouldBindJSON(&statusReq); err != nil {
+		_ = c.Error(models.Validation("invalid request body: %v", err))
+		return
+	}
 
+	res, err := h.service.SetStatus(ctx, c.Param("username"), &statusReq)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
 }
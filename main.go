@@ -1,85 +1,385 @@
 package main
 
 import (
-	"database/sql"
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/MarNawar/carZone/config"
 	"github.com/MarNawar/carZone/driver"
+	adminHandler "github.com/MarNawar/carZone/handler/admin"
+	auditHandler "github.com/MarNawar/carZone/handler/audit"
 	carHandler "github.com/MarNawar/carZone/handler/car"
 	engineHandler "github.com/MarNawar/carZone/handler/engine"
+	healthHandler "github.com/MarNawar/carZone/handler/health"
 	loginHandler "github.com/MarNawar/carZone/handler/login"
+	"github.com/MarNawar/carZone/logging"
+	"github.com/MarNawar/carZone/metrics"
 	"github.com/MarNawar/carZone/middleware"
+	"github.com/MarNawar/carZone/models"
+	auditService "github.com/MarNawar/carZone/service/audit"
 	carService "github.com/MarNawar/carZone/service/car"
 	engineService "github.com/MarNawar/carZone/service/engine"
+	healthService "github.com/MarNawar/carZone/service/health"
+	purgeService "github.com/MarNawar/carZone/service/purge"
+	tokenService "github.com/MarNawar/carZone/service/token"
+	userService "github.com/MarNawar/carZone/service/user"
+	"github.com/MarNawar/carZone/store"
+	auditStore "github.com/MarNawar/carZone/store/audit"
 	carStore "github.com/MarNawar/carZone/store/car"
 	engineStore "github.com/MarNawar/carZone/store/engine"
+	"github.com/MarNawar/carZone/store/instrumented"
+	memoryStore "github.com/MarNawar/carZone/store/memory"
+	"github.com/MarNawar/carZone/store/migrate"
+	tokenStore "github.com/MarNawar/carZone/store/token"
+	userStore "github.com/MarNawar/carZone/store/user"
+	"github.com/MarNawar/carZone/tracing"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
-	err := godotenv.Load()
+	if err := run(); err != nil {
+		var problems config.Problems
+		if errors.As(err, &problems) {
+			fmt.Fprintln(os.Stderr, "Invalid configuration:")
+			for _, problem := range problems {
+				fmt.Fprintln(os.Stderr, "  -", problem)
+			}
+			os.Exit(2)
+		}
+		slog.Error("Exiting", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run starts the service and blocks until it has shut down. Failures are
+// returned rather than exiting on the spot, so the deferred cleanup, closing
+// the database and flushing buffered spans, still runs.
+func run() error {
+	// Settings come from flags, the environment (and .env) and an optional
+	// YAML file given by -config or CONFIG_FILE; see the config package
+	cfg, args, err := config.Load(os.Args[1:])
+	var problems config.Problems
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return nil
+	case errors.As(err, &problems):
+		return err
+	case err != nil:
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Records are JSON on stderr; the level was checked by config.Load
+	logLevel, _ := logging.ParseLevel(cfg.Log.Level)
+	slog.SetDefault(logging.New(os.Stderr, logLevel))
+
+	// The OTLP exporter reads the standard OTEL_EXPORTER_OTLP_* variables
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Log.TracesExporter, os.Stdout)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Error flushing traces", "error", err)
+		}
+	}()
+
+	var carStoreImpl store.CarStoreInterface
+	var engineStoreImpl store.EngineStoreInterface
+	var userStoreImpl store.UserStoreInterface
+	var tokenStoreImpl store.TokenStoreInterface
+	var auditStoreImpl store.AuditStoreInterface
+	var readinessChecks []healthService.Check
+
+	// STORE_BACKEND=memory runs without Postgres, for demos and handler tests
+	switch cfg.Store.Backend {
+	case config.BackendMemory:
+		if len(args) > 0 && args[0] == "migrate" {
+			return errors.New("migrate commands need STORE_BACKEND=postgres")
+		}
+		slog.Warn("Using in-memory store, data will not survive a restart")
+		memStore := memoryStore.New()
+		carStoreImpl = memStore
+		engineStoreImpl = memStore
+		userStoreImpl = memStore
+		tokenStoreImpl = memStore
+		auditStoreImpl = memStore
+	case config.BackendPostgres:
+		if err := driver.InitDB(cfg.Database); err != nil {
+			return err
+		}
+		defer driver.CloseDB()
+
+		db := driver.GetDB()
+		migrator, err := migrate.New(db)
+		if err != nil {
+			return fmt.Errorf("failed to load migrations: %w", err)
+		}
+
+		// migrate up|down|status|seed runs a one-off command instead of the server
+		if len(args) > 0 && args[0] == "migrate" {
+			if err := runMigrateCommand(migrator, args[1:]); err != nil {
+				return fmt.Errorf("migrate command failed: %w", err)
+			}
+			return nil
+		}
+
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		for _, m := range applied {
+			slog.Info("Applied migration", "version", m.Version, "name", m.Name)
+		}
+
+		if cfg.Store.SeedData {
+			if err := migrator.Seed(context.Background()); err != nil {
+				return fmt.Errorf("failed to seed database: %w", err)
+			}
+			slog.Info("Seeded database with dummy data")
+		}
+
+		// Ready once the database answers and the schema is up to date
+		readinessChecks = []healthService.Check{
+			{Name: "database", Run: db.PingContext},
+			{Name: "migrations", Run: func(ctx context.Context) error {
+				pending, err := migrator.Pending(ctx)
+				if err != nil {
+					return err
+				}
+				if len(pending) > 0 {
+					return fmt.Errorf("%d migrations pending", len(pending))
+				}
+				return nil
+			}},
+		}
+
+		carStoreImpl = carStore.New(db)
+		engineStoreImpl = engineStore.New(db)
+		userStoreImpl = userStore.New(db)
+		tokenStoreImpl = tokenStore.New(db)
+		auditStoreImpl = auditStore.New(db)
 	}
 
-	driver.InitDB()
-	defer driver.CloseDB()
+	// Every store call is timed for /metrics, whichever backend is in use
+	carStoreImpl = instrumented.NewCarStore(carStoreImpl)
+	engineStoreImpl = instrumented.NewEngineStore(engineStoreImpl)
+	userStoreImpl = instrumented.NewUserStore(userStoreImpl)
+	tokenStoreImpl = instrumented.NewTokenStore(tokenStoreImpl)
+	auditStoreImpl = instrumented.NewAuditStore(auditStoreImpl)
+	metrics.RegisterInventory(carStoreImpl.CountInventory)
+
+	carService := carService.NewCarService(carStoreImpl)
+	engineService := engineService.NewEngineService(engineStoreImpl)
+	userService := userService.NewUserService(userStoreImpl)
+	tokenService := tokenService.NewTokenService(tokenStoreImpl, userStoreImpl)
+	auditService := auditService.NewAuditService(auditStoreImpl)
 
-	db := driver.GetDB()
-	carStore := carStore.New(db)
-	carService := carService.NewCarService(carStore)
+	healthService := healthService.NewHealthService(readinessChecks...)
 
-	engineStore := engineStore.New(db)
-	engineService := engineService.NewEngineService(engineStore)
+	// SIGINT or SIGTERM stops the purge job and starts a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	purgeService := purgeService.NewPurgeService(carStoreImpl, engineStoreImpl, cfg.Purge.Retention)
+	if cfg.Purge.Interval > 0 {
+		go purgeService.Run(ctx, cfg.Purge.Interval)
+	}
+
+	if cfg.Auth.AdminUsername != "" {
+		_, err := userService.EnsureUser(context.Background(), &models.UserRequest{
+			UserName: cfg.Auth.AdminUsername,
+			Password: cfg.Auth.AdminPassword,
+		}, models.RoleAdmin)
+		if err != nil {
+			return fmt.Errorf("failed to create admin user: %w", err)
+		}
+	}
 
 	carHandler := carHandler.NewCarHandler(carService)
 	engineHandler := engineHandler.NewEngineHandler(engineService)
+	adminHandler := adminHandler.NewAdminHandler(purgeService)
+	auditHandler := auditHandler.NewAuditHandler(auditService)
+	healthHandler := healthHandler.NewHealthHandler(healthService)
+	var keys *middleware.KeySet
+	if cfg.Auth.JWTKeysDir != "" {
+		keys, err = middleware.LoadKeySet(cfg.Auth.JWTKeysDir, cfg.Auth.JWTActiveKID)
+	} else {
+		slog.Warn("JWT_KEYS_DIR not set, signing tokens with an ephemeral key")
+		keys, err = middleware.GenerateKeySet()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load JWT signing keys: %w", err)
+	}
 
-	router := gin.New()
-	router.Use(gin.Logger())
-
+	loginHandler := loginHandler.NewLoginHandler(userService, tokenService, keys)
 
-	schemaFile := "./store/schema.sql"
-	if err := executeSchemaFile(db, schemaFile); err != nil{
-		log.Fatal("error while executing the schema file")
+	// gin's own debug output goes to the debug log instead of stdout
+	gin.DebugPrintRouteFunc = func(method, path, handler string, _ int) {
+		slog.Debug("Registered route", "method", method, "path", path, "handler", handler)
+	}
+	gin.DebugPrintFunc = func(format string, values ...interface{}) {
+		slog.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)))
 	}
 
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
+	router.Use(middleware.RequestLogger())
+	router.Use(middleware.Metrics())
+	router.Use(middleware.ErrorHandler())
+
+	// Each route group gets its own deadline; imports, exports and purges
+	// share the longer bulk one
+	readTimeout := middleware.Timeout(cfg.Server.ReadTimeout)
+	writeTimeout := middleware.Timeout(cfg.Server.WriteTimeout)
+	bulkTimeout := middleware.Timeout(cfg.Server.BulkTimeout)
+
 	//login
-	router.POST("/login", loginHandler.Login)
-	router.Use(middleware.AuthMiddleware())
+	public := router.Group("", writeTimeout)
+	public.POST("/login", loginHandler.HandleLogin)
+	public.POST("/register", loginHandler.HandleRegister)
+	public.POST("/token/refresh", loginHandler.HandleRefresh)
+	router.GET("/.well-known/jwks.json", loginHandler.HandleJWKS)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/healthz", healthHandler.HandleLiveness)
+	router.GET("/readyz", readTimeout, healthHandler.HandleReadiness)
+	router.Use(middleware.AuthMiddleware(keys, tokenService))
+
+	router.POST("/logout", writeTimeout, loginHandler.HandleLogout)
+
+	canRead := middleware.RequireRole(models.RoleViewer, models.RoleEditor, models.RoleAdmin)
+	canWrite := middleware.RequireRole(models.RoleEditor, models.RoleAdmin)
+	isAdmin := middleware.RequireRole(models.RoleAdmin)
+
+	// user router
+	users := router.Group("/user", writeTimeout)
+	users.PUT("/password", loginHandler.HandleChangePassword)
+	users.PUT("/:username/role", isAdmin, loginHandler.HandleSetRole)
+	users.PUT("/:username/status", isAdmin, loginHandler.HandleSetStatus)
+
+	// admin router
+	router.POST("/admin/purge", isAdmin, bulkTimeout, adminHandler.HandlePurge)
+	router.GET("/audit", isAdmin, readTimeout, auditHandler.HandleListAudit)
+
+	reads := router.Group("", canRead, readTimeout)
+	writes := router.Group("", canWrite, writeTimeout)
+	exports := router.Group("", canRead, bulkTimeout)
+	imports := router.Group("", canWrite, bulkTimeout)
 
 	// car router
-	router.GET("/car/:id", carHandler.HandleGetCarByID)
-	router.GET("/cars", carHandler.HandleGetCarByBrand)
-	router.POST("/car", carHandler.HandleCreateCar)
-	router.PUT("/car/:id", carHandler.HandleUpdateCar)
-	router.DELETE("/car/:id", carHandler.HandleDeleteCar)
+	reads.GET("/car/:id", carHandler.HandleGetCarByID)
+	reads.GET("/cars", carHandler.HandleListCars)
+	exports.GET("/cars/export", carHandler.HandleExportCars)
+	writes.POST("/car", carHandler.HandleCreateCar)
+	imports.POST("/cars/import", carHandler.HandleImportCars)
+	writes.PUT("/car/:id", carHandler.HandleUpdateCar)
+	writes.PATCH("/car/:id", carHandler.HandlePatchCar)
+	writes.DELETE("/car/:id", carHandler.HandleDeleteCar)
+	writes.POST("/car/:id/restore", carHandler.HandleRestoreCar)
 
 	// engine router
-	router.GET("/engine/:id", engineHandler.HandleGetEngineByID)
-	router.POST("/engine", engineHandler.HandleCreateEngine)
-	router.PUT("/engine/:id", engineHandler.HandleUpdateEngine)
-	router.DELETE("/engine/:id", engineHandler.HandleDeleteEngine)
+	reads.GET("/engine/:id", engineHandler.HandleGetEngineByID)
+	reads.GET("/engines", engineHandler.HandleListEngines)
+	reads.GET("/engine/:id/cars", carHandler.HandleGetCarsByEngine)
+	writes.POST("/engine", engineHandler.HandleCreateEngine)
+	imports.POST("/engines/import", engineHandler.HandleImportEngines)
+	writes.PUT("/engine/:id", engineHandler.HandleUpdateEngine)
+	writes.PATCH("/engine/:id", engineHandler.HandlePatchEngine)
+	writes.DELETE("/engine/:id", engineHandler.HandleDeleteEngine)
+	writes.POST("/engine/:id/restore", engineHandler.HandleRestoreEngine)
 
-	err = router.Run(":8080")
-	if err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{
+		Addr:              cfg.Server.Addr(),
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Listening", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("failed to start server: %w", err)
+	case <-ctx.Done():
 	}
+	// A second signal kills the process straight away
+	stop()
+
+	// Stop taking new requests and let in-flight ones finish, so their
+	// transactions commit or roll back before the database is closed by
+	// the deferred driver.CloseDB
+	slog.Info("Shutting down, draining in-flight requests", "timeout", cfg.Server.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// Closing the connections cancels the requests' contexts, which
+		// rolls back whatever they still had open
+		slog.Error("Requests still running after shutdown timeout, closing their connections", "error", err)
+		_ = server.Close()
+	}
+	slog.Info("Server stopped")
+	return nil
 }
 
-func executeSchemaFile(db *sql.DB, fileName string)error{
-	sqlFile, err := os.ReadFile(fileName)
-	if err != nil{
-		log.Printf("Error reading file: %v", err)
-		return err;
+func runMigrateCommand(migrator *migrate.Migrator, args []string) error {
+	ctx := context.Background()
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s migrate up|down|status|seed", os.Args[0])
 	}
 
-	_, err = db.Exec(string(sqlFile))
-	if err != nil{
-		log.Printf("Error executing SQL: %v", err)
-		return err
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		for _, m := range applied {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			fmt.Println("No applied migrations to revert")
+			return nil
+		}
+		fmt.Printf("Reverted %04d_%s\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-40s %s\n", st.Version, st.Name, state)
+		}
+	case "seed":
+		if err := migrator.Seed(ctx); err != nil {
+			return err
+		}
+		fmt.Println("Seeded database with dummy data")
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down, status or seed", args[0])
 	}
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,99 @@
+// Package metrics defines the Prometheus metrics served at /metrics: HTTP
+// traffic, store call latency and inventory gauges. Database pool stats are
+// registered by the driver package.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "carzone"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to serve HTTP requests, by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	storeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_call_duration_seconds",
+		Help:      "Time taken by store methods, by store, method and outcome.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"store", "method", "outcome"})
+)
+
+// ObserveRequest records one served HTTP request. route is the matched
+// route pattern, never the raw path, so IDs do not blow up the label set.
+func ObserveRequest(method, route string, status string, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, status).Inc()
+	httpDuration.WithLabelValues(method, route, status).Observe(duration.Seconds())
+}
+
+// ObserveStore records a store call that started at start; err is read when
+// the call returns, so it is meant to be deferred:
+//
+//	defer metrics.ObserveStore("car", "GetCarById", time.Now(), &err)
+func ObserveStore(store, method string, start time.Time, err *error) {
+	outcome := "ok"
+	if *err != nil {
+		outcome = "error"
+	}
+	storeDuration.WithLabelValues(store, method, outcome).Observe(time.Since(start).Seconds())
+}
+
+// InventorySource reports live car counts grouped by brand and fuel type.
+type InventorySource func(context.Context) ([]models.InventoryCount, error)
+
+// inventoryTimeout bounds the query run on each scrape.
+const inventoryTimeout = 5 * time.Second
+
+var carsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "cars"),
+	"Cars in the inventory, not counting deleted ones, by brand and fuel type.",
+	[]string{"brand", "fuel_type"}, nil,
+)
+
+// inventoryCollector queries the counts on every scrape, so the gauges are
+// never stale and nothing has to update them on each mutation.
+type inventoryCollector struct {
+	source InventorySource
+}
+
+// RegisterInventory exports the car counts from source as gauges.
+func RegisterInventory(source InventorySource) {
+	prometheus.MustRegister(inventoryCollector{source: source})
+}
+
+func (c inventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- carsDesc
+}
+
+func (c inventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), inventoryTimeout)
+	defer cancel()
+
+	counts, err := c.source(ctx)
+	if err != nil {
+		slog.Error("Error collecting inventory metrics", "error", err)
+		ch <- prometheus.NewInvalidMetric(carsDesc, err)
+		return
+	}
+	for _, count := range counts {
+		ch <- prometheus.MustNewConstMetric(carsDesc, prometheus.GaugeValue, float64(count.Cars), count.Brand, count.FuelType)
+	}
+}
@@ -47,68 +47,101 @@
 package middleware
 
 import (
-	"net/http"
-	"os"
+	"context"
+	"fmt"
 	"strings"
 	"time"
 
+	"github.com/MarNawar/carZone/models"
 	"github.com/gin-gonic/gin"
 	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
 type Claims struct {
-	Username string `json:"username"`
+	Username string      `json:"username"`
+	Role     models.Role `json:"role"`
 	jwt.RegisteredClaims
 }
 
-var SECRET_KEY string = os.Getenv("SECRET_KEY")
+// AccessTokenTTL is kept short; clients renew through POST /token/refresh.
+const AccessTokenTTL = 15 * time.Minute
 
-var jwtKey = []byte(SECRET_KEY)
+// RevocationChecker reports whether an access token has been revoked
+// before its expiry, by jti.
+type RevocationChecker interface {
+	IsRevoked(context.Context, string) (bool, error)
+}
 
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(keys *KeySet, revocations RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			abortWithError(c, models.Unauthorized("Authorization header required"))
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		claims := &Claims{}
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFunc, jwt.WithValidMethods(validMethods))
+
+		if err != nil || !token.Valid || claims.ID == "" {
+			abortWithError(c, models.Unauthorized("Invalid Token"))
+			return
+		}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Token"})
-			c.Abort()
+		revoked, err := revocations.IsRevoked(c.Request.Context(), claims.ID)
+		if err != nil {
+			abortWithError(c, fmt.Errorf("failed to check token revocation: %w", err))
+			return
+		}
+		if revoked {
+			abortWithError(c, models.Unauthorized("Token has been revoked"))
 			return
 		}
 
 		c.Set("username", claims.Username)
+		c.Set("role", string(claims.Role))
+		c.Set("jti", claims.ID)
+		c.Set("token_expires_at", claims.ExpiresAt.Time)
+		// The stores read the actor from the request context for the audit log
+		c.Request = c.Request.WithContext(models.WithActor(c.Request.Context(), claims.Username))
 		c.Next()
 	}
 }
 
-func GenerateToken(userName string)(string, error){
-	expiration := time.Now().Local().Add(time.Hour * 24)
+// RequireRole lets the request through only if the role set by
+// AuthMiddleware is one of roles. It must run after AuthMiddleware.
+func RequireRole(roles ...models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := models.Role(c.GetString("role"))
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		abortWithError(c, models.Forbidden("insufficient role for this operation"))
+	}
+}
 
-	// claims := &jwt.RegisteredClaims{
-	// 	ExpiresAt: jwt.NewNumericDate(expiration),
-	// 	Subject: userName,
-	// 	IssuedAt: jwt.NewNumericDate(time.Now()),
-	// }
+func (k *KeySet) GenerateToken(userName string, role models.Role)(string, error){
+	now := time.Now().Local()
+	expiration := now.Add(AccessTokenTTL)
 
 	claims := &Claims{
 		Username: userName,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   userName,
+			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(expiration),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString(jwtKey)
+	signedToken, err := k.sign(claims)
 	if err != nil{
 		return "", err
 	}
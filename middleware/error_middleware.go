@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorBody is the envelope every error response uses:
+// {"error": {"code": "not_found", "message": "..."}}, plus "details" for
+// errors that carry them.
+type ErrorBody struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// statusClientClosedRequest is recorded for requests the client abandoned.
+const statusClientClosedRequest = 499
+
+var errorStatuses = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{models.ErrNotFound, http.StatusNotFound, "not_found"},
+	{models.ErrValidation, http.StatusBadRequest, "validation_error"},
+	{models.ErrInvalidID, http.StatusBadRequest, "invalid_id"},
+	{models.ErrConflict, http.StatusConflict, "conflict"},
+	{models.ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{models.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{models.ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed"},
+	{models.ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	{models.ErrTimeout, http.StatusGatewayTimeout, "timeout"},
+}
+
+// ErrorHandler turns the last error a handler attached with c.Error into a
+// JSON response. Errors that do not wrap a models error kind are logged and
+// reported as a bare 500 so internals never leak to the client.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Taken before Timeout wraps it: that context is always canceled by
+		// the time the chain returns here, so only this one tells whether
+		// the client went away
+		ctx := c.Request.Context()
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		// The client went away, so nothing failed on our side and nobody
+		// reads the response; 499 is nginx's status for this
+		if errors.Is(ctx.Err(), context.Canceled) {
+			slog.InfoContext(ctx, "request canceled by client",
+				"method", c.Request.Method,
+				"route", c.FullPath(),
+			)
+			c.Status(statusClientClosedRequest)
+			return
+		}
+		// Whatever failed once the deadline passed, the deadline is the cause
+		if store.IsTimeout(ctx, err) || errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+			err = models.Timeout("request did not complete in time")
+		}
+
+		status, body := translateError(err)
+		if status == http.StatusInternalServerError {
+			slog.ErrorContext(ctx, "request failed",
+				"method", c.Request.Method,
+				"route", c.FullPath(),
+				"error", err,
+			)
+		}
+		c.JSON(status, ErrorResponse{Error: body})
+	}
+}
+
+func translateError(err error) (int, ErrorBody) {
+	var appErr *models.Error
+	if errors.As(err, &appErr) {
+		for _, e := range errorStatuses {
+			if errors.Is(err, e.kind) {
+				return e.status, ErrorBody{Code: e.code, Message: err.Error(), Details: appErr.Details}
+			}
+		}
+	}
+	return http.StatusInternalServerError, ErrorBody{Code: "internal_error", Message: "internal server error"}
+}
+
+// abortWithError records err for ErrorHandler and stops the chain.
+func abortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+func TestErrorHandlerStatuses(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(c *gin.Context, cancel context.CancelFunc) error
+		want    int
+	}{
+		{
+			name: "typed error behind a timeout",
+			handler: func(c *gin.Context, _ context.CancelFunc) error {
+				return models.Validation("bad input")
+			},
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "untyped error",
+			handler: func(c *gin.Context, _ context.CancelFunc) error {
+				return fmt.Errorf("boom")
+			},
+			want: http.StatusInternalServerError,
+		},
+		{
+			name: "deadline passed",
+			handler: func(c *gin.Context, _ context.CancelFunc) error {
+				<-c.Request.Context().Done()
+				return fmt.Errorf("failed to fetch cars: %w", c.Request.Context().Err())
+			},
+			want: http.StatusGatewayTimeout,
+		},
+		{
+			name: "statement timeout",
+			handler: func(c *gin.Context, _ context.CancelFunc) error {
+				return fmt.Errorf("failed to fetch cars: %w", &pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"})
+			},
+			want: http.StatusGatewayTimeout,
+		},
+		{
+			name: "client went away",
+			handler: func(c *gin.Context, cancel context.CancelFunc) error {
+				cancel()
+				return fmt.Errorf("failed to fetch cars: %w", &pq.Error{Code: "57014", Message: "canceling statement due to user request"})
+			},
+			want: statusClientClosedRequest,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/", Timeout(20*time.Millisecond), func(c *gin.Context) {
+				_ = c.Error(tt.handler(c, cancel))
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/MarNawar/carZone/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics counts and times every request by method, route pattern and
+// status. Requests that match no route share one label so scanners hitting
+// random paths cannot grow the series without bound.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveRequest(c.Request.Method, route, strconv.Itoa(c.Writer.Status()), time.Since(start))
+	}
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditPurge   AuditAction = "purge"
+)
+
+type AuditEntity string
+
+const (
+	AuditEntityCar    AuditEntity = "car"
+	AuditEntityEngine AuditEntity = "engine"
+)
+
+const (
+	DefaultAuditLimit = 50
+	MaxAuditLimit     = 500
+)
+
+// SystemActor is recorded for changes made outside an authenticated request.
+const SystemActor = "system"
+
+// AuditEntry records one change to a car or engine. Before is empty for
+// creations and After for deletions and purges.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    AuditAction     `json:"action"`
+	Entity    AuditEntity     `json:"entity"`
+	EntityID  uuid.UUID       `json:"entity_id"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AuditFilter selects entries for GET /audit, newest first. BeforeID pages
+// backwards from the last entry of the previous page.
+type AuditFilter struct {
+	Entity   AuditEntity
+	EntityID string
+	Actor    string
+	BeforeID int64
+	Limit    int
+}
+
+func ValidateAuditFilter(filter AuditFilter) error {
+	switch filter.Entity {
+	case "", AuditEntityCar, AuditEntityEngine:
+	default:
+		return Validation("entity must be one of: car, engine")
+	}
+	if filter.EntityID != "" {
+		if filter.Entity == "" {
+			return Validation("id needs an entity")
+		}
+		if _, err := ParseID(filter.EntityID); err != nil {
+			return err
+		}
+	}
+	if filter.Limit < 1 || filter.Limit > MaxAuditLimit {
+		return Validation("limit must be between 1 and %d", MaxAuditLimit)
+	}
+	if filter.BeforeID < 0 {
+		return Validation("before must be a positive audit entry ID")
+	}
+	return nil
+}
+
+type actorKey struct{}
+
+// WithActor returns a context carrying the user name that mutations made
+// with it are attributed to.
+func WithActor(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, actorKey{}, username)
+}
+
+// ActorFromContext returns the user set by WithActor, or SystemActor.
+func ActorFromContext(ctx context.Context) string {
+	if username, ok := ctx.Value(actorKey{}).(string); ok && username != "" {
+		return username
+	}
+	return SystemActor
+}
@@ -1,7 +1,6 @@
 package models
 
 import (
-	"errors"
 	"strconv"
 	"time"
 
@@ -18,6 +17,7 @@ type Car struct {
 	Price     float64   `json:"price"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	Version   int64     `json:"version"`
 }
 
 
@@ -32,7 +32,7 @@ type CarRequest struct{
 
 func validateName(name string)error{
 	if name == ""{
-		return errors.New("name is required")
+		return Validation("name is required")
 	}
 
 	return nil
@@ -40,23 +40,23 @@ func validateName(name string)error{
 
 func validateYear(year string)error{
 	if year == ""{
-		return errors.New("year is required");
+		return Validation("year is required");
 	}
 	yearInt, err := strconv.Atoi(year)
 	if err != nil{
-		return  errors.New("year must be a valid number")
+		return  Validation("year must be a valid number")
 	}
 	currentYear := time.Now().Year()
 
 	if yearInt<1886 || yearInt> currentYear{
-		return  errors.New("year must be a between 1886 and current year")
+		return  Validation("year must be a between 1886 and current year")
 	}
 	return nil
 }
 
 func validateBrand(brand string) error{
 	if brand == ""{
-		return errors.New("brand is required");
+		return Validation("brand is required");
 	}
 	return nil
 }
@@ -69,24 +69,24 @@ func validateFuelType(fuelType string)error{
 		}
 	}
 
-	return errors.New("FuelType Must be one of: Persol, Diesel, Electric, Hybrid")
+	return Validation("FuelType Must be one of: Persol, Diesel, Electric, Hybrid")
 }
 
 func validateEngine(engine Engine)error{
 	if engine.EngineID == uuid.Nil{
-		return errors.New("EngineID is required")
+		return Validation("EngineID is required")
 	}
 
 	if engine.Displacement <= 0{
-		return errors.New("displacement must be greater than zero")
+		return Validation("displacement must be greater than zero")
 	}
 
 	if engine.NoOfCylinders <= 0{
-		return errors.New("noOfCylinders must be greater than zero")
+		return Validation("noOfCylinders must be greater than zero")
 	}
 
 	if engine.CarRange <= 0{
-		return errors.New("noOfCylinders must be greater than zero")
+		return Validation("noOfCylinders must be greater than zero")
 	}
 
 	return nil
@@ -94,7 +94,7 @@ func validateEngine(engine Engine)error{
 
 func validatePrice(price float64)error{
 	if price <= 0{
-		return errors.New("price must be greater than zero")
+		return Validation("price must be greater than zero")
 	}
 
 	return nil
@@ -126,4 +126,11 @@ func ValidateRequest(carReq CarRequest)error{
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// InventoryCount is the number of live cars of one brand and fuel type.
+type InventoryCount struct {
+	Brand    string `json:"brand"`
+	FuelType string `json:"fuel_type"`
+	Cars     int64  `json:"cars"`
+}
@@ -1,8 +1,6 @@
 package models
 
 import (
-	"errors"
-
 	"github.com/google/uuid"
 )
 
@@ -11,6 +9,7 @@ type Engine struct {
 	Displacement int64 `json:"displacement"`
 	NoOfCylinders int64 `json:"noOfCylinders"`
 	CarRange int64 `json:"carRange"`
+	Version int64 `json:"version,omitempty"`
 }
 
 type EngineRequest struct {
@@ -21,21 +20,21 @@ type EngineRequest struct {
 
 func validateDisplacement(displacement int64)error{
 	if displacement <= 0{
-		return errors.New("displacement must be greater than zero")
+		return Validation("displacement must be greater than zero")
 	}
 	return nil
 }
 
 func validateNoOfCylinders(noOfCylinders int64)error{
 	if noOfCylinders <= 0{
-		return errors.New("noOfCylinder must be greater than zero")
+		return Validation("noOfCylinder must be greater than zero")
 	}
 	return nil
 }
 
 func validateCarRange(carRange int64)error{
 	if carRange <= 0{
-		return errors.New("carRange must be greater than zero")
+		return Validation("carRange must be greater than zero")
 	}
 	return nil
 }
@@ -51,4 +50,41 @@ func ValidateEngineRequest(EngineReq EngineRequest)error{
 		return err
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// EngineDeleteOptions says what happens to the cars using an engine when it
+// is deleted. With neither option set the deletion is refused while any car
+// still uses the engine.
+type EngineDeleteOptions struct {
+	Cascade    bool
+	ReassignTo string
+}
+
+// EngineInUseDetails is returned with the conflict error when an engine
+// cannot be deleted because cars still use it.
+type EngineInUseDetails struct {
+	CarIDs []uuid.UUID `json:"car_ids"`
+}
+
+func ValidateEngineDeleteOptions(id string, options EngineDeleteOptions) error {
+	if options.ReassignTo == "" {
+		return nil
+	}
+	if options.Cascade {
+		return Validation("cascade and reassign_to cannot be used together")
+	}
+	target, err := ParseID(options.ReassignTo)
+	if err != nil {
+		return err
+	}
+	if source, _ := uuid.Parse(id); target == source {
+		return Validation("cannot reassign cars to the engine being deleted")
+	}
+	return nil
+}
+
+// EngineInUse reports the cars blocking an engine deletion.
+func EngineInUse(id string, carIDs []uuid.UUID) error {
+	err := Conflict("engine with ID %s is used by %d car(s); delete it with cascade=true or reassign its cars with reassign_to", id, len(carIDs))
+	return WithDetails(err, EngineInUseDetails{CarIDs: carIDs})
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Error kinds. Every error returned by the store and service layers that the
+// client should see wraps one of these, so handlers can check them with
+// errors.Is and the error middleware can pick the HTTP status.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrValidation   = errors.New("validation failed")
+	ErrConflict     = errors.New("conflict")
+	ErrInvalidID    = errors.New("invalid id")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrTimeout              = errors.New("timeout")
+)
+
+// Error is a client-facing error: Message is safe to return in a response
+// and Kind is one of the sentinel errors above. Details, when set, is
+// returned alongside the message so clients can act on the error.
+type Error struct {
+	Kind    error
+	Message string
+	Details interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func NotFound(format string, args ...interface{}) error {
+	return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func Validation(format string, args ...interface{}) error {
+	return &Error{Kind: ErrValidation, Message: fmt.Sprintf(format, args...)}
+}
+
+func Conflict(format string, args ...interface{}) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// WithDetails attaches details to a client-facing error built by one of the
+// constructors above. Other errors are returned unchanged.
+func WithDetails(err error, details interface{}) error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		appErr.Details = details
+	}
+	return err
+}
+
+func InvalidID(format string, args ...interface{}) error {
+	return &Error{Kind: ErrInvalidID, Message: fmt.Sprintf(format, args...)}
+}
+
+func Unauthorized(format string, args ...interface{}) error {
+	return &Error{Kind: ErrUnauthorized, Message: fmt.Sprintf(format, args...)}
+}
+
+func Forbidden(format string, args ...interface{}) error {
+	return &Error{Kind: ErrForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+func PreconditionFailed(format string, args ...interface{}) error {
+	return &Error{Kind: ErrPreconditionFailed, Message: fmt.Sprintf(format, args...)}
+}
+
+func UnsupportedMediaType(format string, args ...interface{}) error {
+	return &Error{Kind: ErrUnsupportedMediaType, Message: fmt.Sprintf(format, args...)}
+}
+
+func Timeout(format string, args ...interface{}) error {
+	return &Error{Kind: ErrTimeout, Message: fmt.Sprintf(format, args...)}
+}
+
+// StaleVersion reports an If-Match version that no longer matches the row.
+func StaleVersion(entity string, id string, expected, current int64) error {
+	return PreconditionFailed("%s with ID %s is at version %d, not %d; fetch it again and retry", entity, id, current, expected)
+}
+
+// ParseID parses a car or engine ID, reporting ErrInvalidID if it is not a UUID.
+func ParseID(id string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, InvalidID("%q is not a valid UUID", id)
+	}
+	return parsed, nil
+}
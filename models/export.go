@@ -0,0 +1,248 @@
+package models
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ExportFormat string
+
+const (
+	ExportCSV    ExportFormat = "csv"
+	ExportNDJSON ExportFormat = "ndjson"
+	ExportXLSX   ExportFormat = "xlsx"
+)
+
+func ParseExportFormat(format string) (ExportFormat, error) {
+	switch ExportFormat(format) {
+	case "", ExportCSV:
+		return ExportCSV, nil
+	case ExportNDJSON, ExportXLSX:
+		return ExportFormat(format), nil
+	}
+	return "", Validation("format must be one of: csv, ndjson, xlsx")
+}
+
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportNDJSON:
+		return "application/x-ndjson"
+	case ExportXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// CarExporter writes cars one at a time in an export format. Close must be
+// called to finish the file.
+type CarExporter interface {
+	Write(Car) error
+	Close() error
+}
+
+// carExportColumns are the columns of the CSV and XLSX exports, matching the
+// fields of carExportRow.
+var carExportColumns = []string{
+	"id", "name", "year", "brand", "fuel_type", "price",
+	"engine_id", "displacement", "no_of_cylinders", "car_range",
+	"created_at", "updated_at", "version",
+}
+
+// carExportRow flattens a car and its engine. Numbers stay numbers so the
+// XLSX export gives spreadsheets numeric cells.
+func carExportRow(car Car) []interface{} {
+	return []interface{}{
+		car.ID.String(), car.Name, car.Year, car.Brand, car.FuelType, car.Price,
+		car.Engine.EngineID.String(), car.Engine.Displacement, car.Engine.NoOfCylinders, car.Engine.CarRange,
+		car.CreatedAt.UTC().Format(time.RFC3339), car.UpdatedAt.UTC().Format(time.RFC3339), car.Version,
+	}
+}
+
+// NewCarExporter starts an export in format on w, writing the header right
+// away.
+func NewCarExporter(w io.Writer, format ExportFormat) (CarExporter, error) {
+	switch format {
+	case ExportNDJSON:
+		buffered := bufio.NewWriter(w)
+		return &ndjsonCarExporter{buffered: buffered, encoder: json.NewEncoder(buffered)}, nil
+	case ExportXLSX:
+		return newXLSXCarExporter(w)
+	}
+	exporter := &csvCarExporter{writer: csv.NewWriter(w)}
+	if err := exporter.writer.Write(carExportColumns); err != nil {
+		return nil, err
+	}
+	return exporter, nil
+}
+
+type csvCarExporter struct {
+	writer *csv.Writer
+}
+
+func (e *csvCarExporter) Write(car Car) error {
+	row := carExportRow(car)
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = csvCell(value)
+	}
+	return e.writer.Write(record)
+}
+
+func (e *csvCarExporter) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// csvCell formats a value for CSV. Text starting like a formula is prefixed
+// with a quote so spreadsheets opening the file show it instead of running it.
+func csvCell(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+	return ""
+}
+
+type ndjsonCarExporter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+func (e *ndjsonCarExporter) Write(car Car) error {
+	return e.encoder.Encode(car)
+}
+
+func (e *ndjsonCarExporter) Close() error {
+	return e.buffered.Flush()
+}
+
+// The fixed parts of a one-sheet workbook. Only the sheet itself depends on
+// the data, so it is streamed into the zip last.
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Cars" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`},
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="1"><fill><patternFill patternType="none"/></fill></fills>` +
+		`<borders count="1"><border/></borders>` +
+		`<cellStyleXfs count="1"><xf/></cellStyleXfs>` +
+		`<cellXfs count="1"><xf/></cellXfs>` +
+		`</styleSheet>`},
+}
+
+const (
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxCarExporter writes a minimal XLSX workbook with archive/zip, one
+// sheet row per car, using inline strings so no shared string table has to
+// be kept in memory.
+type xlsxCarExporter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+}
+
+func newXLSXCarExporter(w io.Writer) (*xlsxCarExporter, error) {
+	archive := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	exporter := &xlsxCarExporter{zip: archive, sheet: bufio.NewWriter(f)}
+	if _, err := exporter.sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+
+	header := make([]interface{}, len(carExportColumns))
+	for i, column := range carExportColumns {
+		header[i] = column
+	}
+	if err := exporter.writeRow(header); err != nil {
+		return nil, err
+	}
+	return exporter, nil
+}
+
+func (e *xlsxCarExporter) Write(car Car) error {
+	return e.writeRow(carExportRow(car))
+}
+
+func (e *xlsxCarExporter) writeRow(values []interface{}) error {
+	e.sheet.WriteString("<row>")
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			e.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(e.sheet, []byte(v)); err != nil {
+				return err
+			}
+			e.sheet.WriteString("</t></is></c>")
+		case float64:
+			e.sheet.WriteString("<c><v>" + strconv.FormatFloat(v, 'f', -1, 64) + "</v></c>")
+		case int64:
+			e.sheet.WriteString("<c><v>" + strconv.FormatInt(v, 10) + "</v></c>")
+		}
+	}
+	// bufio.Writer keeps the first error and returns it from every later call
+	_, err := e.sheet.WriteString("</row>")
+	return err
+}
+
+func (e *xlsxCarExporter) Close() error {
+	if _, err := e.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
@@ -0,0 +1,8 @@
+package models
+
+// Readiness reports whether the service can take traffic, and the outcome
+// of each check behind that answer.
+type Readiness struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
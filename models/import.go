@@ -0,0 +1,312 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type ImportFormat string
+
+const (
+	ImportCSV    ImportFormat = "csv"
+	ImportNDJSON ImportFormat = "ndjson"
+)
+
+// ImportMode says what happens to the valid rows of an import when others
+// fail: ImportAtomic inserts nothing, ImportBestEffort inserts them anyway.
+type ImportMode string
+
+const (
+	ImportAtomic     ImportMode = "atomic"
+	ImportBestEffort ImportMode = "best_effort"
+)
+
+type ImportRowStatus string
+
+const (
+	ImportCreated ImportRowStatus = "created"
+	ImportFailed  ImportRowStatus = "failed"
+	// ImportSkipped marks a valid row not inserted because an atomic import
+	// had failures elsewhere.
+	ImportSkipped ImportRowStatus = "skipped"
+)
+
+// MaxImportRows bounds a single import, since rows are held in memory until
+// they are validated.
+const MaxImportRows = 50000
+
+// ImportRowResult reports what happened to one row; Row counts data rows
+// from 1, not counting the CSV header.
+type ImportRowResult struct {
+	Row    int             `json:"row"`
+	Status ImportRowStatus `json:"status"`
+	ID     *uuid.UUID      `json:"id,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	Mode    ImportMode        `json:"mode"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// Fail marks a row as failed with the client-facing message of err.
+func (r *ImportReport) Fail(index int, err error) {
+	r.Rows[index].Status = ImportFailed
+	r.Rows[index].Error = ClientMessage(err)
+	r.Failed++
+}
+
+// Create marks a row as inserted with the given ID.
+func (r *ImportReport) Create(index int, id uuid.UUID) {
+	r.Rows[index].Status = ImportCreated
+	r.Rows[index].ID = &id
+	r.Created++
+}
+
+// Skip marks a valid row as not inserted.
+func (r *ImportReport) Skip(index int) {
+	r.Rows[index].Status = ImportSkipped
+}
+
+// ClientMessage returns the message of a client-facing error, or a generic
+// one for anything else so internals never end up in a response.
+func ClientMessage(err error) string {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Message
+	}
+	return "internal server error"
+}
+
+func ParseImportMode(mode string) (ImportMode, error) {
+	switch ImportMode(mode) {
+	case "", ImportAtomic:
+		return ImportAtomic, nil
+	case ImportBestEffort:
+		return ImportBestEffort, nil
+	}
+	return "", Validation("mode must be one of: atomic, best_effort")
+}
+
+// ImportFormatFor picks the format from the format query parameter, falling
+// back to the request content type.
+func ImportFormatFor(format string, contentType string) (ImportFormat, error) {
+	switch ImportFormat(format) {
+	case ImportCSV, ImportNDJSON:
+		return ImportFormat(format), nil
+	case "":
+	default:
+		return "", Validation("format must be one of: csv, ndjson")
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return ImportCSV, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return ImportNDJSON, nil
+	}
+	return "", UnsupportedMediaType("import accepts text/csv or application/x-ndjson, not %q", contentType)
+}
+
+// ImportRow is one record of an import stream: a CSV record keyed by the
+// header, or an NDJSON line. Err is set when the record itself is malformed.
+type ImportRow struct {
+	Number int
+	Fields map[string]string
+	JSON   []byte
+	Err    error
+}
+
+// ImportReader reads rows from a CSV or NDJSON stream one at a time.
+type ImportReader struct {
+	format  ImportFormat
+	columns []string
+	csv     *csv.Reader
+	header  []string
+	lines   *bufio.Scanner
+	number  int
+}
+
+// NewImportReader reads rows in format from r. For CSV the header must name
+// only columns from columns, in any order.
+func NewImportReader(r io.Reader, format ImportFormat, columns []string) *ImportReader {
+	reader := &ImportReader{format: format, columns: columns}
+	if format == ImportCSV {
+		reader.csv = csv.NewReader(r)
+		reader.csv.TrimLeadingSpace = true
+	} else {
+		reader.lines = bufio.NewScanner(r)
+		reader.lines.Buffer(make([]byte, 64*1024), 1024*1024)
+	}
+	return reader
+}
+
+// Next returns the next row, or io.EOF at the end of the stream. Any other
+// error means the stream as a whole cannot be read.
+func (r *ImportReader) Next() (ImportRow, error) {
+	if r.format == ImportCSV {
+		return r.nextCSV()
+	}
+	return r.nextNDJSON()
+}
+
+func (r *ImportReader) nextCSV() (ImportRow, error) {
+	if r.header == nil {
+		header, err := r.csv.Read()
+		if err == io.EOF {
+			return ImportRow{}, Validation("CSV import needs a header row")
+		} else if err != nil {
+			return ImportRow{}, Validation("invalid CSV header: %v", err)
+		}
+		if err := r.checkHeader(header); err != nil {
+			return ImportRow{}, err
+		}
+		r.header = header
+	}
+
+	record, err := r.csv.Read()
+	if err == io.EOF {
+		return ImportRow{}, io.EOF
+	}
+	r.number++
+	row := ImportRow{Number: r.number}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		row.Err = Validation("invalid CSV record: %v", parseErr.Err)
+		return row, nil
+	} else if err != nil {
+		return ImportRow{}, err
+	}
+
+	row.Fields = make(map[string]string, len(r.header))
+	for i, column := range r.header {
+		row.Fields[column] = strings.TrimSpace(record[i])
+	}
+	return row, nil
+}
+
+func (r *ImportReader) checkHeader(header []string) error {
+	seen := make(map[string]bool, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		header[i] = column
+		if !contains(r.columns, column) {
+			return Validation("unknown CSV column %q; expected %s", column, strings.Join(r.columns, ", "))
+		}
+		if seen[column] {
+			return Validation("duplicate CSV column %q", column)
+		}
+		seen[column] = true
+	}
+	return nil
+}
+
+func (r *ImportReader) nextNDJSON() (ImportRow, error) {
+	for r.lines.Scan() {
+		line := bytes.TrimSpace(r.lines.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		r.number++
+		// The scanner reuses its buffer
+		return ImportRow{Number: r.number, JSON: append([]byte(nil), line...)}, nil
+	}
+	if err := r.lines.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return ImportRow{}, Validation("NDJSON line %d is longer than 1MB", r.number+1)
+		}
+		return ImportRow{}, err
+	}
+	return ImportRow{}, io.EOF
+}
+
+// CarImportColumns are the CSV columns of a car import.
+var CarImportColumns = []string{"name", "year", "brand", "fuel_type", "engine_id", "price"}
+
+// EngineImportColumns are the CSV columns of an engine import.
+var EngineImportColumns = []string{"displacement", "noofcylinders", "carrange"}
+
+// DecodeCarRow turns an import row into a car request. NDJSON rows use the
+// same body as POST /car; CSV rows name the engine with engine_id alone.
+func DecodeCarRow(row ImportRow) (CarRequest, error) {
+	var carReq CarRequest
+	if row.Err != nil {
+		return carReq, row.Err
+	}
+	if row.JSON != nil {
+		if err := json.Unmarshal(row.JSON, &carReq); err != nil {
+			return carReq, Validation("invalid JSON: %v", err)
+		}
+		return carReq, nil
+	}
+
+	carReq.Name = row.Fields["name"]
+	carReq.Year = row.Fields["year"]
+	carReq.Brand = row.Fields["brand"]
+	carReq.FuelType = row.Fields["fuel_type"]
+	if value := row.Fields["engine_id"]; value != "" {
+		engineID, err := ParseID(value)
+		if err != nil {
+			return carReq, err
+		}
+		carReq.Engine.EngineID = engineID
+	}
+	if value := row.Fields["price"]; value != "" {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return carReq, Validation("price must be a number")
+		}
+		carReq.Price = price
+	}
+	return carReq, nil
+}
+
+// DecodeEngineRow turns an import row into an engine request. CSV columns
+// are the JSON field names, lowercased.
+func DecodeEngineRow(row ImportRow) (EngineRequest, error) {
+	var engineReq EngineRequest
+	if row.Err != nil {
+		return engineReq, row.Err
+	}
+	if row.JSON != nil {
+		if err := json.Unmarshal(row.JSON, &engineReq); err != nil {
+			return engineReq, Validation("invalid JSON: %v", err)
+		}
+		return engineReq, nil
+	}
+
+	fields := []struct {
+		column string
+		value  *int64
+	}{
+		{"displacement", &engineReq.Displacement},
+		{"noofcylinders", &engineReq.NoOfCylinders},
+		{"carrange", &engineReq.CarRange},
+	}
+	for _, field := range fields {
+		value := row.Fields[field.column]
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return engineReq, Validation("%s must be a whole number", field.column)
+		}
+		*field.value = n
+	}
+	return engineReq, nil
+}
@@ -0,0 +1,271 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// SortField is one entry of a sort= parameter such as "-price,year".
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+type CarFilter struct {
+	Brand           string
+	Name            string
+	FuelType        string
+	MinYear         int
+	MaxYear         int
+	MinPrice        float64
+	MaxPrice        float64
+	MinDisplacement int64
+	MaxDisplacement int64
+	MinCylinders    int64
+	MaxCylinders    int64
+	MinRange        int64
+	MaxRange        int64
+	Sort            []SortField
+	Limit           int
+	Cursor          string
+}
+
+type CarPage struct {
+	Cars       []Car  `json:"cars"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	TotalCount int64  `json:"total_count"`
+}
+
+type EngineFilter struct {
+	MinDisplacement int64
+	MaxDisplacement int64
+	MinCylinders    int64
+	MaxCylinders    int64
+	MinRange        int64
+	MaxRange        int64
+	Sort            []SortField
+	Limit           int
+	Cursor          string
+}
+
+type EnginePage struct {
+	Engines    []Engine `json:"engines"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+	TotalCount int64    `json:"total_count"`
+}
+
+// carSortFields and engineSortFields are the fields the list endpoints can
+// sort on. Stores map them to their own columns or struct fields.
+var (
+	carSortFields    = []string{"name", "year", "brand", "fuel_type", "price", "created_at", "updated_at"}
+	engineSortFields = []string{"displacement", "cylinders", "range"}
+)
+
+// ParseSort parses a comma separated list of field names, each optionally
+// prefixed with "-" for descending order. An empty string means no explicit
+// order, so rows come back by ID.
+func ParseSort(raw string, allowed []string) ([]SortField, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		field := SortField{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !contains(allowed, field.Field) {
+			return nil, Validation("cannot sort by %q, expected one of: %s", field.Field, strings.Join(allowed, ", "))
+		}
+		if seen[field.Field] {
+			return nil, Validation("sort field %q given more than once", field.Field)
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// ParseCarSort parses a GET /cars sort= parameter, oldest first by default.
+func ParseCarSort(raw string) ([]SortField, error) {
+	if raw == "" {
+		return []SortField{{Field: "created_at"}}, nil
+	}
+	return ParseSort(raw, carSortFields)
+}
+
+func ParseEngineSort(raw string) ([]SortField, error) {
+	return ParseSort(raw, engineSortFields)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortKey(sort []SortField) string {
+	parts := make([]string, len(sort))
+	for i, f := range sort {
+		parts[i] = f.Field
+		if f.Desc {
+			parts[i] = "-" + f.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Cursor marks the last row of a page: the values of its sort fields, in
+// sort order, and its ID as the final tie-breaker.
+type Cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	ID     string   `json:"id"`
+}
+
+func EncodeCursor(sort []SortField, values []string, id uuid.UUID) string {
+	data, _ := json.Marshal(Cursor{Sort: sortKey(sort), Values: values, ID: id.String()})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses an opaque cursor and checks it was issued for the same
+// sort order.
+func DecodeCursor(raw string, sort []SortField) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil || json.Unmarshal(data, &cursor) != nil {
+		return cursor, Validation("cursor is malformed")
+	}
+	if cursor.Sort != sortKey(sort) || len(cursor.Values) != len(sort) {
+		return cursor, Validation("cursor does not match the requested sort order")
+	}
+	if _, err := uuid.Parse(cursor.ID); err != nil {
+		return cursor, Validation("cursor is malformed")
+	}
+	return cursor, nil
+}
+
+// CarSortValue renders a car's sort field the way it is stored in a cursor.
+func CarSortValue(car Car, field string) string {
+	switch field {
+	case "name":
+		return car.Name
+	case "year":
+		return car.Year
+	case "brand":
+		return car.Brand
+	case "fuel_type":
+		return car.FuelType
+	case "price":
+		return strconv.FormatFloat(car.Price, 'f', -1, 64)
+	case "created_at":
+		return car.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updated_at":
+		return car.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+func CarCursor(car Car, sort []SortField) string {
+	values := make([]string, len(sort))
+	for i, f := range sort {
+		values[i] = CarSortValue(car, f.Field)
+	}
+	return EncodeCursor(sort, values, car.ID)
+}
+
+// EngineSortValue renders an engine's sort field the way it is stored in a
+// cursor.
+func EngineSortValue(engine Engine, field string) string {
+	switch field {
+	case "displacement":
+		return strconv.FormatInt(engine.Displacement, 10)
+	case "cylinders":
+		return strconv.FormatInt(engine.NoOfCylinders, 10)
+	case "range":
+		return strconv.FormatInt(engine.CarRange, 10)
+	}
+	return ""
+}
+
+func EngineCursor(engine Engine, sort []SortField) string {
+	values := make([]string, len(sort))
+	for i, f := range sort {
+		values[i] = EngineSortValue(engine, f.Field)
+	}
+	return EncodeCursor(sort, values, engine.EngineID)
+}
+
+func ValidateCarFilter(filter CarFilter) error {
+	if filter.Limit < 1 || filter.Limit > MaxPageLimit {
+		return Validation("limit must be between 1 and %d", MaxPageLimit)
+	}
+	if err := validateCarRanges(filter); err != nil {
+		return err
+	}
+	if filter.Cursor != "" {
+		if _, err := DecodeCursor(filter.Cursor, filter.Sort); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateCarExportFilter checks the filters of an export, which has no
+// pages and so no limit or cursor.
+func ValidateCarExportFilter(filter CarFilter) error {
+	return validateCarRanges(filter)
+}
+
+func validateCarRanges(filter CarFilter) error {
+	if filter.MaxYear != 0 && filter.MinYear > filter.MaxYear {
+		return Validation("year_min must not be greater than year_max")
+	}
+	if filter.MaxPrice != 0 && filter.MinPrice > filter.MaxPrice {
+		return Validation("price_min must not be greater than price_max")
+	}
+	if filter.MaxDisplacement != 0 && filter.MinDisplacement > filter.MaxDisplacement {
+		return Validation("displacement_min must not be greater than displacement_max")
+	}
+	if filter.MaxCylinders != 0 && filter.MinCylinders > filter.MaxCylinders {
+		return Validation("cylinders_min must not be greater than cylinders_max")
+	}
+	if filter.MaxRange != 0 && filter.MinRange > filter.MaxRange {
+		return Validation("range_min must not be greater than range_max")
+	}
+	return nil
+}
+
+func ValidateEngineFilter(filter EngineFilter) error {
+	if filter.Limit < 1 || filter.Limit > MaxPageLimit {
+		return Validation("limit must be between 1 and %d", MaxPageLimit)
+	}
+	if filter.MaxDisplacement != 0 && filter.MinDisplacement > filter.MaxDisplacement {
+		return Validation("displacement_min must not be greater than displacement_max")
+	}
+	if filter.MaxCylinders != 0 && filter.MinCylinders > filter.MaxCylinders {
+		return Validation("cylinders_min must not be greater than cylinders_max")
+	}
+	if filter.MaxRange != 0 && filter.MinRange > filter.MaxRange {
+		return Validation("range_min must not be greater than range_max")
+	}
+	if filter.Cursor != "" {
+		if _, err := DecodeCursor(filter.Cursor, filter.Sort); err != nil {
+			return err
+		}
+	}
+	return nil
+}
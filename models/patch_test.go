@@ -0,0 +1,312 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "add member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"/b","value":2}]`,
+			want:  `{"a":1,"b":2}`,
+		},
+		{
+			name:  "add replaces existing member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"/a","value":[1,2]}]`,
+			want:  `{"a":[1,2]}`,
+		},
+		{
+			name:  "add inserts into array",
+			doc:   `{"a":[1,3]}`,
+			patch: `[{"op":"add","path":"/a/1","value":2}]`,
+			want:  `{"a":[1,2,3]}`,
+		},
+		{
+			name:  "add appends with -",
+			doc:   `{"a":[1,2]}`,
+			patch: `[{"op":"add","path":"/a/-","value":3}]`,
+			want:  `{"a":[1,2,3]}`,
+		},
+		{
+			name:  "add to whole document",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"","value":{"b":2}}]`,
+			want:  `{"b":2}`,
+		},
+		{
+			name:    "add without value",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"add","path":"/b"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "add under missing parent",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"add","path":"/x/y","value":1}]`,
+			wantErr: true,
+		},
+		{
+			name:    "add past end of array",
+			doc:     `{"a":[1]}`,
+			patch:   `[{"op":"add","path":"/a/3","value":1}]`,
+			wantErr: true,
+		},
+		{
+			name:  "remove member",
+			doc:   `{"a":1,"b":2}`,
+			patch: `[{"op":"remove","path":"/a"}]`,
+			want:  `{"b":2}`,
+		},
+		{
+			name:  "remove array element",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"remove","path":"/a/1"}]`,
+			want:  `{"a":[1,3]}`,
+		},
+		{
+			name:    "remove missing member",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"remove","path":"/b"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "remove with leading zero index",
+			doc:     `{"a":[1,2]}`,
+			patch:   `[{"op":"remove","path":"/a/01"}]`,
+			wantErr: true,
+		},
+		{
+			name:  "replace member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"replace","path":"/a","value":"x"}]`,
+			want:  `{"a":"x"}`,
+		},
+		{
+			name:  "replace array element",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"replace","path":"/a/1","value":9}]`,
+			want:  `{"a":[1,9,3]}`,
+		},
+		{
+			name:    "replace missing member",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"replace","path":"/b","value":2}]`,
+			wantErr: true,
+		},
+		{
+			name:  "move member",
+			doc:   `{"a":{"b":1},"c":{}}`,
+			patch: `[{"op":"move","from":"/a/b","path":"/c/d"}]`,
+			want:  `{"a":{},"c":{"d":1}}`,
+		},
+		{
+			name:  "move array element",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"move","from":"/a/0","path":"/a/-"}]`,
+			want:  `{"a":[2,3,1]}`,
+		},
+		{
+			name:    "move into itself",
+			doc:     `{"a":{"b":1}}`,
+			patch:   `[{"op":"move","from":"/a","path":"/a/b"}]`,
+			wantErr: true,
+		},
+		{
+			name:  "copy member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"copy","from":"/a","path":"/b"}]`,
+			want:  `{"a":1,"b":1}`,
+		},
+		{
+			name:  "copy does not alias the source",
+			doc:   `{"c":{"d":"original"}}`,
+			patch: `[{"op":"copy","from":"/c","path":"/k"},{"op":"replace","path":"/k/d","value":"changed"}]`,
+			want:  `{"c":{"d":"original"},"k":{"d":"changed"}}`,
+		},
+		{
+			name:  "copy array does not alias the source",
+			doc:   `{"a":[1,2]}`,
+			patch: `[{"op":"copy","from":"/a","path":"/b"},{"op":"replace","path":"/b/0","value":9}]`,
+			want:  `{"a":[1,2],"b":[9,2]}`,
+		},
+		{
+			name:    "copy from missing member",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"copy","from":"/x","path":"/b"}]`,
+			wantErr: true,
+		},
+		{
+			name:  "test matches",
+			doc:   `{"a":{"b":[1,"x"]}}`,
+			patch: `[{"op":"test","path":"/a","value":{"b":[1,"x"]}}]`,
+			want:  `{"a":{"b":[1,"x"]}}`,
+		},
+		{
+			name:    "test does not match",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"test","path":"/a","value":2}]`,
+			wantErr: true,
+		},
+		{
+			name:    "failed test rejects the whole patch",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"replace","path":"/a","value":2},{"op":"test","path":"/a","value":1}]`,
+			wantErr: true,
+		},
+		{
+			name:  "~1 escapes /",
+			doc:   `{"a/b":1}`,
+			patch: `[{"op":"replace","path":"/a~1b","value":2}]`,
+			want:  `{"a/b":2}`,
+		},
+		{
+			name:  "~0 escapes ~",
+			doc:   `{"a~b":1}`,
+			patch: `[{"op":"remove","path":"/a~0b"}]`,
+			want:  `{}`,
+		},
+		{
+			name:  "~01 is ~1, not /",
+			doc:   `{"~1":1,"/":2}`,
+			patch: `[{"op":"remove","path":"/~01"}]`,
+			want:  `{"/":2}`,
+		},
+		{
+			name:    "path without leading /",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"remove","path":"a"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown op",
+			doc:     `{"a":1}`,
+			patch:   `[{"op":"frobnicate","path":"/a"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "not a patch array",
+			doc:     `{"a":1}`,
+			patch:   `{"op":"remove","path":"/a"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := JSONPatch([]byte(tt.doc), []byte(tt.patch))
+			checkPatchResult(t, got, err, tt.want, tt.wantErr)
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{
+			name:  "replace member",
+			doc:   `{"a":1,"b":2}`,
+			patch: `{"a":3}`,
+			want:  `{"a":3,"b":2}`,
+		},
+		{
+			name:  "null deletes member",
+			doc:   `{"a":1,"b":2}`,
+			patch: `{"a":null}`,
+			want:  `{"b":2}`,
+		},
+		{
+			name:  "null deletes nested member",
+			doc:   `{"a":{"b":1,"c":2}}`,
+			patch: `{"a":{"b":null}}`,
+			want:  `{"a":{"c":2}}`,
+		},
+		{
+			name:  "null for missing member is ignored",
+			doc:   `{"a":1}`,
+			patch: `{"x":null}`,
+			want:  `{"a":1}`,
+		},
+		{
+			name:  "objects merge recursively",
+			doc:   `{"a":{"b":1}}`,
+			patch: `{"a":{"c":2}}`,
+			want:  `{"a":{"b":1,"c":2}}`,
+		},
+		{
+			name:  "arrays are replaced whole",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `{"a":[4]}`,
+			want:  `{"a":[4]}`,
+		},
+		{
+			name:  "object replaces scalar",
+			doc:   `{"a":1}`,
+			patch: `{"a":{"b":null,"c":2}}`,
+			want:  `{"a":{"c":2}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+			checkPatchResult(t, got, err, tt.want, false)
+		})
+	}
+}
+
+func TestApplyPatchContentTypes(t *testing.T) {
+	doc := []byte(`{"a":1}`)
+
+	got, err := ApplyPatch(doc, []byte(`{"a":2}`), "application/json")
+	checkPatchResult(t, got, err, `{"a":2}`, false)
+
+	got, err = ApplyPatch(doc, []byte(`[{"op":"remove","path":"/a"}]`), JSONPatchContentType)
+	checkPatchResult(t, got, err, `{}`, false)
+
+	_, err = ApplyPatch(doc, []byte(`{"a":2}`), "text/plain")
+	if !errors.Is(err, ErrUnsupportedMediaType) {
+		t.Errorf("text/plain: err = %v, want unsupported media type", err)
+	}
+}
+
+// checkPatchResult compares a patched document with want as JSON values, so
+// member order does not matter. Patch failures must be validation errors.
+func checkPatchResult(t *testing.T, got []byte, err error, want string, wantErr bool) {
+	t.Helper()
+	if wantErr {
+		if !errors.Is(err, ErrValidation) {
+			t.Fatalf("err = %v, want a validation error", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("patched document is not JSON: %v", err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("bad want %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
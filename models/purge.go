@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// PurgeResult reports what one purge run permanently removed.
+type PurgeResult struct {
+	DeletedBefore time.Time `json:"deleted_before"`
+	CarsPurged    int64     `json:"cars_purged"`
+	EnginesPurged int64     `json:"engines_purged"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+var ErrInvalidToken = &Error{Kind: ErrUnauthorized, Message: "token is invalid, expired or revoked"}
+
+type RefreshToken struct {
+	TokenHash string
+	UserName  string
+	ExpiresAt time.Time
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
@@ -1,6 +1,90 @@
 package models
 
-type User struct{
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUserNotFound = &Error{Kind: ErrNotFound, Message: "user does not exist"}
+	ErrUserExists   = &Error{Kind: ErrConflict, Message: "user already exists"}
+)
+
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+type User struct {
+	ID                  uuid.UUID `json:"id"`
+	UserName            string    `json:"userName"`
+	PasswordHash        string    `json:"-"`
+	Role                Role      `json:"role"`
+	Disabled            bool      `json:"disabled"`
+	Locked              bool      `json:"locked"`
+	FailedLoginAttempts int       `json:"-"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+type UserRequest struct {
 	UserName string `json:"userName"`
 	Password string `json:"password"`
-}
\ No newline at end of file
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+
+type UserRoleRequest struct {
+	Role Role `json:"role"`
+}
+
+type UserStatusRequest struct {
+	Disabled bool `json:"disabled"`
+	Locked   bool `json:"locked"`
+}
+
+func ValidateRole(role Role) error {
+	switch role {
+	case RoleViewer, RoleEditor, RoleAdmin:
+		return nil
+	}
+	return Validation("role must be one of: viewer, editor, admin")
+}
+
+func validateUserName(userName string) error {
+	if userName == "" {
+		return Validation("userName is required")
+	}
+	if len(userName) < 3 || len(userName) > 50 {
+		return Validation("userName must be between 3 and 50 characters")
+	}
+	return nil
+}
+
+func ValidatePassword(password string) error {
+	if len(password) < 8 {
+		return Validation("password must be at least 8 characters")
+	}
+	// bcrypt ignores everything past 72 bytes
+	if len(password) > 72 {
+		return Validation("password must be at most 72 characters")
+	}
+	return nil
+}
+
+func ValidateUserRequest(userReq UserRequest) error {
+	if err := validateUserName(userReq.UserName); err != nil {
+		return err
+	}
+	if err := ValidatePassword(userReq.Password); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ETag renders a car or engine version as a strong entity tag.
+func ETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ParseIfMatch turns an If-Match header into the version an update expects.
+// An empty header or "*" returns 0, meaning any version. A header listing
+// several tags, or one that is not a version ETag, can never match, and
+// neither can a weak W/ tag since If-Match uses strong comparison.
+func ParseIfMatch(header string) (int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.HasPrefix(header, "W/") {
+		return 0, PreconditionFailed("If-Match %s is a weak entity tag and never matches", header)
+	}
+
+	if len(header) >= 2 && header[0] == '"' && header[len(header)-1] == '"' {
+		if version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64); err == nil && version > 0 {
+			return version, nil
+		}
+	}
+	return 0, PreconditionFailed("If-Match %s does not match the current version", header)
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int64
+		wantErr bool
+	}{
+		{header: "", want: 0},
+		{header: "*", want: 0},
+		{header: ` "3" `, want: 3},
+		{header: ETag(42), want: 42},
+		{header: `W/"3"`, wantErr: true},
+		{header: `"3", "4"`, wantErr: true},
+		{header: `3`, wantErr: true},
+		{header: `"0"`, wantErr: true},
+		{header: `"-1"`, wantErr: true},
+		{header: `"abc"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, err := ParseIfMatch(tt.header)
+			if tt.wantErr {
+				if !errors.Is(err, ErrPreconditionFailed) {
+					t.Fatalf("err = %v, want precondition failed", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store"
+	"github.com/MarNawar/carZone/tracing"
+)
+
+type AuditService struct {
+	store store.AuditStoreInterface
+}
+
+func NewAuditService(store store.AuditStoreInterface) *AuditService {
+	return &AuditService{
+		store: store,
+	}
+}
+
+func (s *AuditService) ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.ListAudit")
+	defer span.End()
+
+	if err := models.ValidateAuditFilter(filter); err != nil {
+		return nil, err
+	}
+	return s.store.ListAudit(ctx, filter)
+}
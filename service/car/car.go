@@ -2,11 +2,18 @@ package car
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 
 	"github.com/MarNawar/carZone/models"
 	"github.com/MarNawar/carZone/store"
+	"github.com/MarNawar/carZone/tracing"
 )
 
+// maxPatchAttempts bounds how often a PATCH without If-Match is reapplied
+// after losing a race with another write.
+const maxPatchAttempts = 3
+
 type CarService struct {
 	store store.CarStoreInterface
 }
@@ -18,6 +25,12 @@ func NewCarService(store store.CarStoreInterface) *CarService {
 }
 
 func (s *CarService) GetCarById(ctx context.Context, id string)(*models.Car, error){
+	ctx, span := tracing.Start(ctx, "CarService.GetCarById")
+	defer span.End()
+
+	if _, err := models.ParseID(id); err != nil{
+		return nil, err
+	}
 	car, err := s.store.GetCarById(ctx, id)
 	if err != nil{
 		return nil, err
@@ -26,6 +39,9 @@ func (s *CarService) GetCarById(ctx context.Context, id string)(*models.Car, err
 }
 
 func (s *CarService)GetCarsByBrand(ctx context.Context, brand string, isEngine bool)([]models.Car, error){
+	ctx, span := tracing.Start(ctx, "CarService.GetCarsByBrand")
+	defer span.End()
+
 	cars, err := s.store.GetCarByBrand(ctx, brand, isEngine)
 
 	if err != nil{
@@ -34,8 +50,46 @@ func (s *CarService)GetCarsByBrand(ctx context.Context, brand string, isEngine b
 	return cars, nil
 }
 
+func (s *CarService)ListCars(ctx context.Context, filter models.CarFilter)(*models.CarPage, error){
+	ctx, span := tracing.Start(ctx, "CarService.ListCars")
+	defer span.End()
+
+	if err := models.ValidateCarFilter(filter); err != nil{
+		return nil, err
+	}
+
+	page, err := s.store.ListCars(ctx, filter)
+	if err != nil{
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ExportCars streams every car matching the filter to fn without paging.
+func (s *CarService)ExportCars(ctx context.Context, filter models.CarFilter, fn func(models.Car) error) error{
+	ctx, span := tracing.Start(ctx, "CarService.ExportCars")
+	defer span.End()
+
+	if err := models.ValidateCarExportFilter(filter); err != nil{
+		return err
+	}
+	return s.store.ExportCars(ctx, filter, fn)
+}
+
+func (s *CarService)GetCarsByEngine(ctx context.Context, engineID string)([]models.Car, error){
+	ctx, span := tracing.Start(ctx, "CarService.GetCarsByEngine")
+	defer span.End()
+
+	if _, err := models.ParseID(engineID); err != nil{
+		return nil, err
+	}
+	return s.store.GetCarsByEngine(ctx, engineID)
+}
 
 func (s *CarService)CreateCar(ctx context.Context, car *models.CarRequest)(*models.Car, error){
+	ctx, span := tracing.Start(ctx, "CarService.CreateCar")
+	defer span.End()
+
 	if err := models.ValidateRequest(*car); err != nil{
 		return nil, err
 	}
@@ -47,21 +101,94 @@ func (s *CarService)CreateCar(ctx context.Context, car *models.CarRequest)(*mode
 }
 
 
-func (s *CarService)UpdateCar(ctx context.Context, id string, car *models.CarRequest)(*models.Car, error){
+func (s *CarService)UpdateCar(ctx context.Context, id string, car *models.CarRequest, expectedVersion int64)(*models.Car, error){
+	ctx, span := tracing.Start(ctx, "CarService.UpdateCar")
+	defer span.End()
+
+	if _, err := models.ParseID(id); err != nil{
+		return nil, err
+	}
 	if err := models.ValidateRequest(*car); err != nil{
 		return nil, err
 	}
-	updatedCar, err := s.store.UpdateCar(ctx, id, car)
+	updatedCar, err := s.store.UpdateCar(ctx, id, car, expectedVersion)
 	if err != nil{
 		return nil, err
 	}
 	return &updatedCar, nil
 }
 
+// PatchCar applies a merge patch or JSON Patch to the stored car and saves
+// the result as a full replacement once it validates. Without If-Match a
+// concurrent write just means patching the fresh copy again.
+func (s *CarService)PatchCar(ctx context.Context, id string, patch []byte, contentType string, expectedVersion int64)(*models.Car, error){
+	ctx, span := tracing.Start(ctx, "CarService.PatchCar")
+	defer span.End()
+
+	if _, err := models.ParseID(id); err != nil{
+		return nil, err
+	}
+	for attempt := 1; ; attempt++{
+		current, err := s.store.GetCarById(ctx, id)
+		if err != nil{
+			return nil, err
+		}
+		if expectedVersion != 0 && current.Version != expectedVersion{
+			return nil, models.StaleVersion("car", id, expectedVersion, current.Version)
+		}
+
+		currentReq := models.CarRequest{
+			Name:     current.Name,
+			Year:     current.Year,
+			Brand:    current.Brand,
+			FuelType: current.FuelType,
+			Engine:   current.Engine,
+			Price:    current.Price,
+		}
+		var car models.CarRequest
+		if err := models.PatchRequest(currentReq, patch, contentType, &car); err != nil{
+			return nil, err
+		}
+		if err := models.ValidateRequest(car); err != nil{
+			return nil, err
+		}
+
+		updatedCar, err := s.store.UpdateCar(ctx, id, &car, current.Version)
+		if errors.Is(err, models.ErrPreconditionFailed) && expectedVersion == 0 && attempt < maxPatchAttempts{
+			slog.DebugContext(ctx, "Concurrent update while patching, retrying", "car_id", id, "attempt", attempt)
+			continue
+		}
+		if err != nil{
+			return nil, err
+		}
+		return &updatedCar, nil
+	}
+}
+
 func ( s *CarService) DeleteCar(ctx context.Context, id string)(*models.Car, error){
+	ctx, span := tracing.Start(ctx, "CarService.DeleteCar")
+	defer span.End()
+
+	if _, err := models.ParseID(id); err != nil{
+		return nil, err
+	}
 	deletedCar, err := s.store.DeleteCar(ctx, id)
 	if err != nil{
 		return nil, err
 	}
 	return &deletedCar, nil
-}
\ No newline at end of file
+}
+
+func ( s *CarService) RestoreCar(ctx context.Context, id string)(*models.Car, error){
+	ctx, span := tracing.Start(ctx, "CarService.RestoreCar")
+	defer span.End()
+
+	if _, err := models.ParseID(id); err != nil{
+		return nil, err
+	}
+	restoredCar, err := s.store.RestoreCar(ctx, id)
+	if err != nil{
+		return nil, err
+	}
+	return &restoredCar, nil
+}
@@ -0,0 +1,140 @@
+package car
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store"
+	"github.com/MarNawar/carZone/tracing"
+	"github.com/google/uuid"
+)
+
+// importRow is a decoded row waiting to be validated and inserted; index
+// points at its result in the report.
+type importRow struct {
+	index  int
+	carReq models.CarRequest
+}
+
+// ImportCars reads every row, validates it against its engine with one
+// engine lookup for the whole import, and inserts the valid rows. In atomic
+// mode they go in one transaction and only if no row failed. In best-effort
+// mode they go batch by batch, and a batch the store rejects is retried row
+// by row so one bad row does not fail the rest of its batch.
+func (s *CarService) ImportCars(ctx context.Context, reader *models.ImportReader, mode models.ImportMode) (report *models.ImportReport, err error) {
+	ctx, span := tracing.Start(ctx, "CarService.ImportCars")
+	defer tracing.End(span, &err)
+
+	defer func() {
+		if err == nil {
+			slog.InfoContext(ctx, "Imported cars", "mode", mode, "total", report.Total, "created", report.Created, "failed", report.Failed)
+		}
+	}()
+
+	report = &models.ImportReport{Mode: mode, Rows: []models.ImportRowResult{}}
+
+	var decoded []importRow
+	var engineIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(report.Rows) == models.MaxImportRows {
+			return nil, models.Validation("an import can have at most %d rows", models.MaxImportRows)
+		}
+
+		report.Rows = append(report.Rows, models.ImportRowResult{Row: row.Number})
+		index := len(report.Rows) - 1
+		carReq, err := models.DecodeCarRow(row)
+		if err != nil {
+			report.Fail(index, err)
+			continue
+		}
+		decoded = append(decoded, importRow{index: index, carReq: carReq})
+		if id := carReq.Engine.EngineID; !seen[id] {
+			seen[id] = true
+			engineIDs = append(engineIDs, id)
+		}
+	}
+	report.Total = len(report.Rows)
+
+	engines, err := s.store.GetEnginesByIDs(ctx, engineIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var valid []importRow
+	for _, row := range decoded {
+		// CSV rows only carry engine_id; validate against the stored engine
+		engineID := row.carReq.Engine.EngineID
+		if engine, ok := engines[engineID]; ok {
+			row.carReq.Engine = engine
+		} else if engineID != uuid.Nil {
+			report.Fail(row.index, models.Validation("engine with ID %s does not exist", engineID))
+			continue
+		}
+		if err := models.ValidateRequest(row.carReq); err != nil {
+			report.Fail(row.index, err)
+			continue
+		}
+		valid = append(valid, row)
+	}
+
+	if mode == models.ImportAtomic {
+		if report.Failed > 0 {
+			for _, row := range valid {
+				report.Skip(row.index)
+			}
+			return report, nil
+		}
+		if err := s.importBatch(ctx, report, valid); err != nil {
+			return nil, err
+		}
+		return report, nil
+	}
+
+	for start := 0; start < len(valid); start += store.CopyBatchSize {
+		batch := valid[start:min(start+store.CopyBatchSize, len(valid))]
+		err := s.importBatch(ctx, report, batch)
+		if err == nil {
+			continue
+		}
+		slog.WarnContext(ctx, "Import batch failed, inserting its rows one by one", "entity", "cars", "rows", len(batch), "error", err)
+		for _, row := range batch {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			car, err := s.store.CreateCar(ctx, &row.carReq)
+			if err != nil {
+				report.Fail(row.index, err)
+				continue
+			}
+			report.Create(row.index, car.ID)
+		}
+	}
+	return report, nil
+}
+
+func (s *CarService) importBatch(ctx context.Context, report *models.ImportReport, rows []importRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	carReqs := make([]models.CarRequest, len(rows))
+	for i, row := range rows {
+		carReqs[i] = row.carReq
+	}
+	cars, err := s.store.ImportCars(ctx, carReqs)
+	if err != nil {
+		return err
+	}
+	for i, row := range rows {
+		report.Create(row.index, cars[i].ID)
+	}
+	return nil
+}
@@ -2,11 +2,18 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 
 	"github.com/MarNawar/carZone/models"
 	"github.com/MarNawar/carZone/store"
+	"github.com/MarNawar/carZone/tracing"
 )
  
+// maxPatchAttempts bounds how often a PATCH without If-Match is reapplied
+// after losing a race with another write.
+const maxPatchAttempts = 3
+
 type EngineService struct {
 	store store.EngineStoreInterface
 }
@@ -19,6 +26,12 @@ func NewEngineService(store store.EngineStoreInterface) *EngineService {
 
 
 func (s *EngineService) GetEngineByID(ctx context.Context, id string)(*models.Engine, error){
+	ctx, span := tracing.Start(ctx, "EngineService.GetEngineByID")
+	defer span.End()
+
+	if _, err := models.ParseID(id); err != nil{
+		return nil, err
+	}
 	engine, err := s.store.EngineById(ctx, id)
 
 	if err != nil{
@@ -27,7 +40,25 @@ func (s *EngineService) GetEngineByID(ctx context.Context, id string)(*models.En
 	return &engine, nil
 }
 
+func (s *EngineService)ListEngines(ctx context.Context, filter models.EngineFilter)(*models.EnginePage, error){
+	ctx, span := tracing.Start(ctx, "EngineService.ListEngines")
+	defer span.End()
+
+	if err := models.ValidateEngineFilter(filter); err != nil{
+		return nil, err
+	}
+
+	page, err := s.store.ListEngines(ctx, filter)
+	if err != nil{
+		return nil, err
+	}
+	return &page, nil
+}
+
 func (s *EngineService)CreateEngine(ctx context.Context, engineReq *models.EngineRequest)(*models.Engine, error){
+	ctx, span := tracing.Start(ctx, "EngineService.CreateEngine")
+	defer span.End()
+
 	err := models.ValidateEngineRequest(*engineReq)
 	if err != nil{
 		return nil, err
@@ -41,13 +72,19 @@ func (s *EngineService)CreateEngine(ctx context.Context, engineReq *models.Engin
 	return &engine, nil
 }
 
-func (s *EngineService)UpdateEngine(ctx context.Context, engineReq *models.EngineRequest, id string)(*models.Engine, error){
+func (s *EngineService)UpdateEngine(ctx context.Context, engineReq *models.EngineRequest, id string, expectedVersion int64)(*models.Engine, error){
+	ctx, span := tracing.Start(ctx, "EngineService.UpdateEngine")
+	defer span.End()
+
+	if _, err := models.ParseID(id); err != nil{
+		return nil, err
+	}
 	err := models.ValidateEngineRequest(*engineReq)
 	if err != nil{
 		return nil, err
 	}
 	
-	engine, err := s.store.EngineUpdate(ctx, id, engineReq)
+	engine, err := s.store.EngineUpdate(ctx, id, engineReq, expectedVersion)
 	
 	if err != nil{
 		return nil, err
@@ -55,11 +92,78 @@ func (s *EngineService)UpdateEngine(ctx context.Context, engineReq *models.Engin
 	return &engine, nil
 }
 
-func (s *EngineService)DeleteEngine(ctx context.Context, id string)(*models.Engine, error){	
-	engine, err := s.store.EngineDelete(ctx, id)
+// PatchEngine applies a merge patch or JSON Patch to the stored engine and
+// saves the result as a full replacement once it validates.
+func (s *EngineService)PatchEngine(ctx context.Context, id string, patch []byte, contentType string, expectedVersion int64)(*models.Engine, error){
+	ctx, span := tracing.Start(ctx, "EngineService.PatchEngine")
+	defer span.End()
+
+	if _, err := models.ParseID(id); err != nil{
+		return nil, err
+	}
+	for attempt := 1; ; attempt++{
+		current, err := s.store.EngineById(ctx, id)
+		if err != nil{
+			return nil, err
+		}
+		if expectedVersion != 0 && current.Version != expectedVersion{
+			return nil, models.StaleVersion("engine", id, expectedVersion, current.Version)
+		}
+
+		currentReq := models.EngineRequest{
+			Displacement:  current.Displacement,
+			NoOfCylinders: current.NoOfCylinders,
+			CarRange:      current.CarRange,
+		}
+		var engineReq models.EngineRequest
+		if err := models.PatchRequest(currentReq, patch, contentType, &engineReq); err != nil{
+			return nil, err
+		}
+		if err := models.ValidateEngineRequest(engineReq); err != nil{
+			return nil, err
+		}
+
+		engine, err := s.store.EngineUpdate(ctx, id, &engineReq, current.Version)
+		if errors.Is(err, models.ErrPreconditionFailed) && expectedVersion == 0 && attempt < maxPatchAttempts{
+			slog.DebugContext(ctx, "Concurrent update while patching, retrying", "engine_id", id, "attempt", attempt)
+			continue
+		}
+		if err != nil{
+			return nil, err
+		}
+		return &engine, nil
+	}
+}
+
+func (s *EngineService)DeleteEngine(ctx context.Context, id string, options models.EngineDeleteOptions)(*models.Engine, error){
+	ctx, span := tracing.Start(ctx, "EngineService.DeleteEngine")
+	defer span.End()
+
+	if _, err := models.ParseID(id); err != nil{
+		return nil, err
+	}
+	if err := models.ValidateEngineDeleteOptions(id, options); err != nil{
+		return nil, err
+	}
+	engine, err := s.store.EngineDelete(ctx, id, options)
 	
 	if err != nil{
 		return nil, err
 	}
 	return &engine, nil
 }
+
+func (s *EngineService)RestoreEngine(ctx context.Context, id string)(*models.Engine, error){
+	ctx, span := tracing.Start(ctx, "EngineService.RestoreEngine")
+	defer span.End()
+
+	if _, err := models.ParseID(id); err != nil{
+		return nil, err
+	}
+	engine, err := s.store.RestoreEngine(ctx, id)
+
+	if err != nil{
+		return nil, err
+	}
+	return &engine, nil
+}
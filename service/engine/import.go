@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store"
+	"github.com/MarNawar/carZone/tracing"
+)
+
+// importRow is a decoded row waiting to be inserted; index points at its
+// result in the report.
+type importRow struct {
+	index     int
+	engineReq models.EngineRequest
+}
+
+// ImportEngines reads and validates every row and inserts the valid ones,
+// the same way as CarService.ImportCars.
+func (s *EngineService) ImportEngines(ctx context.Context, reader *models.ImportReader, mode models.ImportMode) (report *models.ImportReport, err error) {
+	ctx, span := tracing.Start(ctx, "EngineService.ImportEngines")
+	defer tracing.End(span, &err)
+
+	defer func() {
+		if err == nil {
+			slog.InfoContext(ctx, "Imported engines", "mode", mode, "total", report.Total, "created", report.Created, "failed", report.Failed)
+		}
+	}()
+
+	report = &models.ImportReport{Mode: mode, Rows: []models.ImportRowResult{}}
+
+	var valid []importRow
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(report.Rows) == models.MaxImportRows {
+			return nil, models.Validation("an import can have at most %d rows", models.MaxImportRows)
+		}
+
+		report.Rows = append(report.Rows, models.ImportRowResult{Row: row.Number})
+		index := len(report.Rows) - 1
+		engineReq, err := models.DecodeEngineRow(row)
+		if err == nil {
+			err = models.ValidateEngineRequest(engineReq)
+		}
+		if err != nil {
+			report.Fail(index, err)
+			continue
+		}
+		valid = append(valid, importRow{index: index, engineReq: engineReq})
+	}
+	report.Total = len(report.Rows)
+
+	if mode == models.ImportAtomic {
+		if report.Failed > 0 {
+			for _, row := range valid {
+				report.Skip(row.index)
+			}
+			return report, nil
+		}
+		if err := s.importBatch(ctx, report, valid); err != nil {
+			return nil, err
+		}
+		return report, nil
+	}
+
+	for start := 0; start < len(valid); start += store.CopyBatchSize {
+		batch := valid[start:min(start+store.CopyBatchSize, len(valid))]
+		err := s.importBatch(ctx, report, batch)
+		if err == nil {
+			continue
+		}
+		slog.WarnContext(ctx, "Import batch failed, inserting its rows one by one", "entity", "engines", "rows", len(batch), "error", err)
+		for _, row := range batch {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			engine, err := s.store.CreateEngine(ctx, &row.engineReq)
+			if err != nil {
+				report.Fail(row.index, err)
+				continue
+			}
+			report.Create(row.index, engine.EngineID)
+		}
+	}
+	return report, nil
+}
+
+func (s *EngineService) importBatch(ctx context.Context, report *models.ImportReport, rows []importRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	engineReqs := make([]models.EngineRequest, len(rows))
+	for i, row := range rows {
+		engineReqs[i] = row.engineReq
+	}
+	engines, err := s.store.ImportEngines(ctx, engineReqs)
+	if err != nil {
+		return err
+	}
+	for i, row := range rows {
+		report.Create(row.index, engines[i].EngineID)
+	}
+	return nil
+}
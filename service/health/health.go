@@ -0,0 +1,41 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/MarNawar/carZone/models"
+)
+
+// Check reports why a dependency is not usable, or nil if it is.
+type Check struct {
+	Name string
+	Run  func(context.Context) error
+}
+
+// HealthService answers readiness probes by running every check.
+type HealthService struct {
+	checks []Check
+}
+
+func NewHealthService(checks ...Check) *HealthService {
+	return &HealthService{
+		checks: checks,
+	}
+}
+
+// Ready runs every check. Failures are logged but reported to the caller
+// only as "failing", since the probe endpoint is unauthenticated.
+func (s *HealthService) Ready(ctx context.Context) *models.Readiness {
+	readiness := &models.Readiness{Ready: true, Checks: make(map[string]string, len(s.checks))}
+	for _, check := range s.checks {
+		if err := check.Run(ctx); err != nil {
+			slog.WarnContext(ctx, "Readiness check failed", "check", check.Name, "error", err)
+			readiness.Ready = false
+			readiness.Checks[check.Name] = "failing"
+			continue
+		}
+		readiness.Checks[check.Name] = "ok"
+	}
+	return readiness
+}
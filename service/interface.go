@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/MarNawar/carZone/models"
 )
@@ -9,14 +10,51 @@ import (
 type CarServiceInterface interface {
 	GetCarById(context.Context, string) (*models.Car, error)
 	GetCarsByBrand(context.Context, string, bool)([]models.Car, error)
+	ListCars(context.Context, models.CarFilter)(*models.CarPage, error)
+	ExportCars(context.Context, models.CarFilter, func(models.Car) error) error
+	GetCarsByEngine(context.Context, string)([]models.Car, error)
 	CreateCar(context.Context, *models.CarRequest)(*models.Car, error)
-	UpdateCar(context.Context, string, *models.CarRequest)(*models.Car, error)
+	ImportCars(context.Context, *models.ImportReader, models.ImportMode)(*models.ImportReport, error)
+	UpdateCar(context.Context, string, *models.CarRequest, int64)(*models.Car, error)
+	PatchCar(context.Context, string, []byte, string, int64)(*models.Car, error)
 	DeleteCar(context.Context, string)(*models.Car, error)
+	RestoreCar(context.Context, string)(*models.Car, error)
 }
 
 type EngineServiceInterface interface{
 	GetEngineByID(context.Context, string)(*models.Engine, error)
+	ListEngines(context.Context, models.EngineFilter)(*models.EnginePage, error)
 	CreateEngine(context.Context, *models.EngineRequest)(*models.Engine, error)
-	UpdateEngine(context.Context, *models.EngineRequest, string)(*models.Engine, error)
-	DeleteEngine(context.Context, string)(*models.Engine, error)
-}
\ No newline at end of file
+	ImportEngines(context.Context, *models.ImportReader, models.ImportMode)(*models.ImportReport, error)
+	UpdateEngine(context.Context, *models.EngineRequest, string, int64)(*models.Engine, error)
+	PatchEngine(context.Context, string, []byte, string, int64)(*models.Engine, error)
+	DeleteEngine(context.Context, string, models.EngineDeleteOptions)(*models.Engine, error)
+	RestoreEngine(context.Context, string)(*models.Engine, error)
+}
+type UserServiceInterface interface {
+	Login(context.Context, *models.UserRequest) (*models.User, error)
+	Register(context.Context, *models.UserRequest) (*models.User, error)
+	ChangePassword(context.Context, string, *models.ChangePasswordRequest) (*models.User, error)
+	SetRole(context.Context, string, *models.UserRoleRequest) (*models.User, error)
+	SetStatus(context.Context, string, *models.UserStatusRequest) (*models.User, error)
+	EnsureUser(context.Context, *models.UserRequest, models.Role) (*models.User, error)
+}
+
+type TokenServiceInterface interface {
+	CreateRefreshToken(context.Context, string) (string, error)
+	Refresh(context.Context, string) (*models.User, string, error)
+	Revoke(context.Context, string, time.Time, string) error
+	IsRevoked(context.Context, string) (bool, error)
+}
+
+type PurgeServiceInterface interface {
+	Purge(context.Context) (*models.PurgeResult, error)
+}
+
+type AuditServiceInterface interface {
+	ListAudit(context.Context, models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+type HealthServiceInterface interface {
+	Ready(context.Context) *models.Readiness
+}
@@ -0,0 +1,73 @@
+package purge
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store"
+	"github.com/MarNawar/carZone/tracing"
+)
+
+// PurgeService hard-deletes cars and engines that have been soft deleted
+// for longer than the retention period.
+type PurgeService struct {
+	carStore    store.CarStoreInterface
+	engineStore store.EngineStoreInterface
+	retention   time.Duration
+}
+
+func NewPurgeService(carStore store.CarStoreInterface, engineStore store.EngineStoreInterface, retention time.Duration) *PurgeService {
+	return &PurgeService{
+		carStore:    carStore,
+		engineStore: engineStore,
+		retention:   retention,
+	}
+}
+
+func (s *PurgeService) Purge(ctx context.Context) (*models.PurgeResult, error) {
+	ctx, span := tracing.Start(ctx, "PurgeService.Purge")
+	defer span.End()
+
+	result := &models.PurgeResult{DeletedBefore: time.Now().Add(-s.retention)}
+
+	// Cars go first so their engines are no longer referenced
+	cars, err := s.carStore.PurgeCars(ctx, result.DeletedBefore)
+	if err != nil {
+		return nil, err
+	}
+	result.CarsPurged = cars
+
+	engines, err := s.engineStore.PurgeEngines(ctx, result.DeletedBefore)
+	if err != nil {
+		return nil, err
+	}
+	result.EnginesPurged = engines
+
+	return result, nil
+}
+
+// Run purges every interval until ctx is cancelled.
+func (s *PurgeService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := s.Purge(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Error purging deleted rows", "error", err)
+				continue
+			}
+			slog.InfoContext(ctx, "Purged deleted rows",
+				"cars", result.CarsPurged,
+				"engines", result.EnginesPurged,
+				"deleted_before", result.DeletedBefore.Format(time.RFC3339),
+			)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store"
+	"github.com/MarNawar/carZone/tracing"
+)
+
+// RefreshTokenTTL is how long a refresh token can be exchanged for a new
+// access token.
+const RefreshTokenTTL = 7 * 24 * time.Hour
+
+type TokenService struct {
+	store     store.TokenStoreInterface
+	userStore store.UserStoreInterface
+}
+
+func NewTokenService(store store.TokenStoreInterface, userStore store.UserStoreInterface) *TokenService {
+	return &TokenService{
+		store:     store,
+		userStore: userStore,
+	}
+}
+
+// CreateRefreshToken issues a new opaque refresh token for username. Only
+// its hash is stored.
+func (s *TokenService) CreateRefreshToken(ctx context.Context, username string) (string, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.CreateRefreshToken")
+	defer span.End()
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(raw)
+
+	err := s.store.CreateRefreshToken(ctx, models.RefreshToken{
+		TokenHash: hashToken(refreshToken),
+		UserName:  username,
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// Refresh exchanges a refresh token for the user it was issued to and a
+// replacement refresh token. The old refresh token stops working.
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*models.User, string, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.Refresh")
+	defer span.End()
+
+	consumed, err := s.store.ConsumeRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Pick up role changes and refuse accounts disabled since login
+	user, err := s.userStore.GetUserByUsername(ctx, consumed.UserName)
+	if err != nil {
+		return nil, "", err
+	}
+	if user.Disabled || user.Locked {
+		return nil, "", models.ErrInvalidToken
+	}
+
+	newRefreshToken, err := s.CreateRefreshToken(ctx, user.UserName)
+	if err != nil {
+		return nil, "", err
+	}
+	return &user, newRefreshToken, nil
+}
+
+// Revoke kills the access token identified by jti and, if given, the
+// refresh token issued alongside it.
+func (s *TokenService) Revoke(ctx context.Context, jti string, expiresAt time.Time, refreshToken string) error {
+	ctx, span := tracing.Start(ctx, "TokenService.Revoke")
+	defer span.End()
+
+	if err := s.store.RevokeAccessToken(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+	if refreshToken != "" {
+		if err := s.store.RevokeRefreshToken(ctx, hashToken(refreshToken)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TokenService) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "TokenService.IsRevoked")
+	defer span.End()
+
+	return s.store.IsAccessTokenRevoked(ctx, jti)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
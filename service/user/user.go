@@ -0,0 +1,183 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store"
+	"github.com/MarNawar/carZone/tracing"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxFailedLogins is how many wrong passwords in a row lock an account.
+const maxFailedLogins = 5
+
+var (
+	ErrInvalidCredentials = &models.Error{Kind: models.ErrUnauthorized, Message: "provide valid user name or password"}
+	ErrAccountLocked      = &models.Error{Kind: models.ErrForbidden, Message: "account is locked"}
+	ErrAccountDisabled    = &models.Error{Kind: models.ErrForbidden, Message: "account is disabled"}
+)
+
+// dummyHash is compared against when the user does not exist, so unknown
+// and known user names take the same time to reject.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("carZone-dummy-password"), bcrypt.DefaultCost)
+
+type UserService struct {
+	store store.UserStoreInterface
+}
+
+func NewUserService(store store.UserStoreInterface) *UserService {
+	return &UserService{
+		store: store,
+	}
+}
+
+func (s *UserService) Login(ctx context.Context, userReq *models.UserRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Login")
+	defer span.End()
+
+	user, err := s.store.GetUserByUsername(ctx, userReq.UserName)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(userReq.Password))
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(userReq.Password)); err != nil {
+		if _, err := s.store.RecordLoginFailure(ctx, user.UserName, maxFailedLogins); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.Disabled {
+		return nil, ErrAccountDisabled
+	}
+	if user.Locked {
+		return nil, ErrAccountLocked
+	}
+
+	if user.FailedLoginAttempts > 0 {
+		if err := s.store.ResetLoginFailures(ctx, user.UserName); err != nil {
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
+func (s *UserService) Register(ctx context.Context, userReq *models.UserRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Register")
+	defer span.End()
+
+	if err := models.ValidateUserRequest(*userReq); err != nil {
+		return nil, err
+	}
+
+	hash, err := hashPassword(userReq.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Self-registered accounts can only read; an admin grants more
+	user, err := s.store.CreateUser(ctx, userReq.UserName, hash, models.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *UserService) ChangePassword(ctx context.Context, username string, passwordReq *models.ChangePasswordRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ChangePassword")
+	defer span.End()
+
+	if _, err := s.Login(ctx, &models.UserRequest{UserName: username, Password: passwordReq.OldPassword}); err != nil {
+		return nil, err
+	}
+	if err := models.ValidatePassword(passwordReq.NewPassword); err != nil {
+		return nil, err
+	}
+
+	hash, err := hashPassword(passwordReq.NewPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.store.UpdatePassword(ctx, username, hash)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *UserService) SetRole(ctx context.Context, username string, roleReq *models.UserRoleRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.SetRole")
+	defer span.End()
+
+	if err := models.ValidateRole(roleReq.Role); err != nil {
+		return nil, err
+	}
+
+	user, err := s.store.UpdateRole(ctx, username, roleReq.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *UserService) SetStatus(ctx context.Context, username string, statusReq *models.UserStatusRequest) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.SetStatus")
+	defer span.End()
+
+	user, err := s.store.UpdateStatus(ctx, username, statusReq)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// EnsureUser creates the user with the given role if it does not exist yet,
+// or moves an existing one to that role. It is used to bootstrap the first
+// admin account on an empty database.
+func (s *UserService) EnsureUser(ctx context.Context, userReq *models.UserRequest, role models.Role) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.EnsureUser")
+	defer span.End()
+
+	user, err := s.store.GetUserByUsername(ctx, userReq.UserName)
+	if errors.Is(err, models.ErrUserNotFound) {
+		if err := models.ValidateUserRequest(*userReq); err != nil {
+			return nil, err
+		}
+		var hash string
+		if hash, err = hashPassword(userReq.Password); err != nil {
+			return nil, err
+		}
+		user, err = s.store.CreateUser(ctx, userReq.UserName, hash, role)
+		if errors.Is(err, models.ErrUserExists) {
+			// Another replica created it first
+			user, err = s.store.GetUserByUsername(ctx, userReq.UserName)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Role != role {
+		user, err = s.store.UpdateRole(ctx, user.UserName, role)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
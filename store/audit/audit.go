@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store"
+	"github.com/google/uuid"
+)
+
+type AuditStore struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *AuditStore {
+	return &AuditStore{db: db}
+}
+
+// Record writes an audit entry in tx, so it commits or rolls back together
+// with the change it describes. The actor comes from ctx.
+func Record(ctx context.Context, tx *sql.Tx, action models.AuditAction, entity models.AuditEntity, entityID uuid.UUID, before, after interface{}) error {
+	beforeJSON, err := marshalState(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalState(after)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_log (actor, action, entity, entity_id, before_state, after_state)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = tx.ExecContext(ctx, query, models.ActorFromContext(ctx), action, entity, entityID, beforeJSON, afterJSON)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// marshalState encodes a before or after state, leaving nil as SQL NULL.
+func marshalState(state interface{}) (interface{}, error) {
+	if state == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit state: %w", err)
+	}
+	return string(data), nil
+}
+
+func (a AuditStore) ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	entries := []models.AuditEntry{}
+
+	q := &store.Query{}
+	if filter.Entity != "" {
+		q.Add("entity = $%d", filter.Entity)
+	}
+	if filter.EntityID != "" {
+		q.Add("entity_id = $%d", filter.EntityID)
+	}
+	if filter.Actor != "" {
+		q.Add("actor = $%d", filter.Actor)
+	}
+	if filter.BeforeID != 0 {
+		q.Add("id < $%d", filter.BeforeID)
+	}
+	limit := q.Placeholder(filter.Limit)
+
+	query := `SELECT id, actor, action, entity, entity_id, before_state, after_state, created_at FROM audit_log` +
+		q.Where() + fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", limit)
+
+	rows, err := a.db.QueryContext(ctx, query, q.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audit log: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after []byte
+		err := rows.Scan(
+			&entry.ID,
+			&entry.Actor,
+			&entry.Action,
+			&entry.Entity,
+			&entry.EntityID,
+			&before,
+			&after,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entry.Before = before
+		entry.After = after
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Change is one audited row of a bulk operation.
+type Change struct {
+	EntityID uuid.UUID
+	Before   interface{}
+	After    interface{}
+}
+
+// RecordAll writes an audit entry per change in tx with COPY, for bulk
+// operations where an INSERT per row would dominate.
+func RecordAll(ctx context.Context, tx *sql.Tx, action models.AuditAction, entity models.AuditEntity, changes []Change) error {
+	actor := models.ActorFromContext(ctx)
+	rows := make([][]interface{}, 0, len(changes))
+	for _, change := range changes {
+		beforeJSON, err := marshalState(change.Before)
+		if err != nil {
+			return err
+		}
+		afterJSON, err := marshalState(change.After)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, []interface{}{actor, string(action), string(entity), change.EntityID.String(), beforeJSON, afterJSON})
+	}
+
+	columns := []string{"actor", "action", "entity", "entity_id", "before_state", "after_state"}
+	if err := store.CopyIn(ctx, tx, "audit_log", columns, rows); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
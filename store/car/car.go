@@ -3,12 +3,15 @@ package car
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store"
+	"github.com/MarNawar/carZone/store/audit"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type Store struct {
@@ -21,7 +24,7 @@ func New(db *sql.DB) Store {
 
 func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
 	var car models.Car
-	query := `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.engine_id, c.price, c.created_at, c.updated_at, e.id, e.displacement, e.no_of_cylinders, e.car_range FROM car c JOIN engine e ON c.engine_id = e.id WHERE c.id = $1`
+	query := `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.engine_id, c.price, c.created_at, c.updated_at, c.version, e.id, e.displacement, e.no_of_cylinders, e.car_range FROM car c JOIN engine e ON c.engine_id = e.id WHERE c.id = $1 AND c.deleted_at IS NULL`
 
 	row := s.db.QueryRowContext(ctx, query, id)
 
@@ -35,6 +38,7 @@ func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
 		&car.Price,
 		&car.CreatedAt,
 		&car.UpdatedAt,
+		&car.Version,
 		&car.Engine.EngineID,
 		&car.Engine.Displacement,
 		&car.Engine.NoOfCylinders,
@@ -42,10 +46,10 @@ func (s Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
 	)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return car, nil
+		if errors.Is(err, sql.ErrNoRows) {
+			return car, models.NotFound("car with ID %s does not exist", id)
 		}
-		return car, err
+		return car, fmt.Errorf("failed to fetch car: %w", err)
 	}
 	return car, nil
 }
@@ -58,18 +62,18 @@ func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) (
 	if isEngine {
 		query = `
 			SELECT 
-				c.id, c.name, c.year, c.brand, c.fuel_type, c.engine_id, c.price, c.created_at, c.updated_at,
+				c.id, c.name, c.year, c.brand, c.fuel_type, c.engine_id, c.price, c.created_at, c.updated_at, c.version,
 				e.id, e.displacement, e.no_of_cylinders, e.car_range
 			FROM car c
 			LEFT JOIN engine e ON c.engine_id = e.id
-			WHERE c.brand = $1
+			WHERE c.brand = $1 AND c.deleted_at IS NULL
 		`
 	} else {
 		query = `
 			SELECT 
-				id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at
+				id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at, version
 			FROM car
-			WHERE brand = $1
+			WHERE brand = $1 AND deleted_at IS NULL
 		`
 	}
 
@@ -94,6 +98,7 @@ func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) (
 				&car.Price,
 				&car.CreatedAt,
 				&car.UpdatedAt,
+				&car.Version,
 				&car.Engine.EngineID,
 				&car.Engine.Displacement,
 				&car.Engine.NoOfCylinders,
@@ -113,6 +118,7 @@ func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) (
 				&car.Price,
 				&car.CreatedAt,
 				&car.UpdatedAt,
+				&car.Version,
 			)
 			if err != nil {
 				return nil, fmt.Errorf("failed to scan car: %w", err)
@@ -132,19 +138,7 @@ func (s Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) (
 }
 
 
-func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
-	var createdCar models.Car
-
-	// Validate engine existence
-	var engineExists bool
-	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM engine WHERE id = $1)", carReq.Engine.EngineID).Scan(&engineExists)
-	if err != nil {
-		return createdCar, fmt.Errorf("failed to verify engine existence: %w", err)
-	}
-	if !engineExists {
-		return createdCar, fmt.Errorf("engine with ID %s does not exist", carReq.Engine.EngineID)
-	}
-
+func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (createdCar models.Car, err error) {
 	// Prepare car data
 	carID := uuid.New()
 	currentTime := time.Now()
@@ -175,11 +169,16 @@ func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models
 		err = tx.Commit()
 	}()
 
+	// Validate engine existence
+	if err = shareEngine(ctx, tx, carReq.Engine.EngineID); err != nil {
+		return createdCar, err
+	}
+
 	// Insert car into database
 	query := `
 		INSERT INTO car (id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at) 
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) 
-		RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at
+		RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at, version
 	`
 
 	err = tx.QueryRowContext(
@@ -204,74 +203,31 @@ func (s Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models
 		&createdCar.Price,
 		&createdCar.CreatedAt,
 		&createdCar.UpdatedAt,
+		&createdCar.Version,
 	)
 
 	if err != nil {
 		return createdCar, fmt.Errorf("failed to create car: %w", err)
 	}
 
-	return createdCar, nil
-}
-
-
-func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest) (models.Car, error) {
-	var updatedCar models.Car
-
-	// Fetch existing car to validate ID
-	var exists bool
-	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM car WHERE id = $1)", id).Scan(&exists)
-	if err != nil {
-		return updatedCar, fmt.Errorf("failed to check car existence: %w", err)
-	}
-	if !exists {
-		return updatedCar, fmt.Errorf("car with ID %s does not exist", id)
+	if err = audit.Record(ctx, tx, models.AuditCreate, models.AuditEntityCar, createdCar.ID, nil, createdCar); err != nil {
+		return createdCar, err
 	}
 
-	// Start building the dynamic query
-	var queryBuilder strings.Builder
-	queryBuilder.WriteString("UPDATE car SET ")
-	var args []interface{}
-	argID := 1
-
-	if carReq.Name != "" {
-		queryBuilder.WriteString(fmt.Sprintf("name = $%d, ", argID))
-		args = append(args, carReq.Name)
-		argID++
-	}
-	if carReq.Year != "" {
-		queryBuilder.WriteString(fmt.Sprintf("year = $%d, ", argID))
-		args = append(args, carReq.Year)
-		argID++
-	}
-	if carReq.Brand != "" {
-		queryBuilder.WriteString(fmt.Sprintf("brand = $%d, ", argID))
-		args = append(args, carReq.Brand)
-		argID++
-	}
-	if carReq.FuelType != "" {
-		queryBuilder.WriteString(fmt.Sprintf("fuel_type = $%d, ", argID))
-		args = append(args, carReq.FuelType)
-		argID++
-	}
-	if carReq.Engine != (models.Engine{}) {
-		queryBuilder.WriteString(fmt.Sprintf("engine_id = $%d, ", argID))
-		args = append(args, carReq.Engine.EngineID)
-		argID++
-	}
-	if carReq.Price != 0.0 {
-		queryBuilder.WriteString(fmt.Sprintf("price = $%d, ", argID))
-		args = append(args, carReq.Price)
-		argID++
-	}
+	return createdCar, nil
+}
 
-	// Always update the updated_at field
-	queryBuilder.WriteString(fmt.Sprintf("updated_at = $%d ", argID))
-	args = append(args, time.Now())
-	argID++
 
-	// Add the WHERE clause
-	queryBuilder.WriteString(fmt.Sprintf("WHERE id = $%d RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at", argID))
-	args = append(args, id)
+// UpdateCar replaces every field of the car with carReq. A non-zero
+// expectedVersion must match the car's version, checked under the row lock.
+func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, expectedVersion int64) (updatedCar models.Car, err error) {
+	// Every field is replaced; partial updates go through PatchCar in the service
+	query := `
+		UPDATE car
+		SET name = $1, year = $2, brand = $3, fuel_type = $4, engine_id = $5, price = $6, updated_at = $7, version = version + 1
+		WHERE id = $8 AND deleted_at IS NULL
+		RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at, version`
+	args := []interface{}{carReq.Name, carReq.Year, carReq.Brand, carReq.FuelType, carReq.Engine.EngineID, carReq.Price, time.Now(), id}
 
 	// Begin transaction
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -286,8 +242,23 @@ func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarReque
 		err = tx.Commit()
 	}()
 
+	// Validate engine existence, like CreateCar. The engine is locked before
+	// the car, in the same order as EngineDelete, so the two cannot deadlock.
+	if err = shareEngine(ctx, tx, carReq.Engine.EngineID); err != nil {
+		return updatedCar, err
+	}
+
+	// Lock the row and keep its current state for the audit log
+	before, err := lockCar(ctx, tx, id)
+	if err != nil {
+		return updatedCar, err
+	}
+	if expectedVersion != 0 && before.Version != expectedVersion {
+		return updatedCar, models.StaleVersion("car", id, expectedVersion, before.Version)
+	}
+
 	// Execute the query
-	err = tx.QueryRowContext(ctx, queryBuilder.String(), args...).
+	err = tx.QueryRowContext(ctx, query, args...).
 		Scan(
 			&updatedCar.ID,
 			&updatedCar.Name,
@@ -298,19 +269,22 @@ func (s Store) UpdateCar(ctx context.Context, id string, carReq *models.CarReque
 			&updatedCar.Price,
 			&updatedCar.CreatedAt,
 			&updatedCar.UpdatedAt,
+			&updatedCar.Version,
 		)
 
 	if err != nil {
 		return updatedCar, fmt.Errorf("failed to update car: %w", err)
 	}
 
+	if err = audit.Record(ctx, tx, models.AuditUpdate, models.AuditEntityCar, updatedCar.ID, before, updatedCar); err != nil {
+		return updatedCar, err
+	}
+
 	return updatedCar, nil
 }
 
 
-func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
-	var deletedCar models.Car
-
+func (s Store) DeleteCar(ctx context.Context, id string) (deletedCar models.Car, err error) {
 	// Begin transaction
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -325,13 +299,13 @@ func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
 		err = tx.Commit()
 	}()
 
-	// Delete and return the car details
+	// Soft delete and return the car details
 	query := `
-		DELETE FROM car 
-		WHERE id = $1 
-		RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at
+		UPDATE car SET deleted_at = $2, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at, version
 	`
-	err = tx.QueryRowContext(ctx, query, id).Scan(
+	err = tx.QueryRowContext(ctx, query, id, time.Now()).Scan(
 		&deletedCar.ID,
 		&deletedCar.Name,
 		&deletedCar.Year,
@@ -341,15 +315,584 @@ func (s Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
 		&deletedCar.Price,
 		&deletedCar.CreatedAt,
 		&deletedCar.UpdatedAt,
+		&deletedCar.Version,
 	)
 
 	// Handle error when no rows are affected
-	if err == sql.ErrNoRows {
-		return deletedCar, fmt.Errorf("car with ID %s does not exist", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return deletedCar, models.NotFound("car with ID %s does not exist", id)
 	} else if err != nil {
 		return deletedCar, fmt.Errorf("failed to delete car: %w", err)
 	}
 
+	if err = audit.Record(ctx, tx, models.AuditDelete, models.AuditEntityCar, deletedCar.ID, deletedCar, nil); err != nil {
+		return deletedCar, err
+	}
+
 	return deletedCar, nil
 }
 
+// RestoreCar undoes DeleteCar. The car's engine must not be deleted itself.
+func (s Store) RestoreCar(ctx context.Context, id string) (restoredCar models.Car, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return restoredCar, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// The engine is share-locked too, so EngineDelete cannot delete it
+	// between this check and the car coming back
+	var deletedAt sql.NullTime
+	var engineDeleted bool
+	query := `
+		SELECT c.deleted_at, e.deleted_at IS NOT NULL
+		FROM car c
+		JOIN engine e ON c.engine_id = e.id
+		WHERE c.id = $1
+		FOR UPDATE OF c FOR SHARE OF e
+	`
+	err = tx.QueryRowContext(ctx, query, id).Scan(&deletedAt, &engineDeleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return restoredCar, models.NotFound("car with ID %s does not exist", id)
+	} else if err != nil {
+		return restoredCar, fmt.Errorf("failed to fetch car: %w", err)
+	}
+	if !deletedAt.Valid {
+		return restoredCar, models.Conflict("car with ID %s is not deleted", id)
+	}
+	if engineDeleted {
+		return restoredCar, models.Conflict("the engine of car %s is deleted; restore the engine first", id)
+	}
+
+	query = `
+		UPDATE car SET deleted_at = NULL, updated_at = $2, version = version + 1
+		WHERE id = $1
+		RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at, version
+	`
+	err = tx.QueryRowContext(ctx, query, id, time.Now()).Scan(
+		&restoredCar.ID,
+		&restoredCar.Name,
+		&restoredCar.Year,
+		&restoredCar.Brand,
+		&restoredCar.FuelType,
+		&restoredCar.Engine.EngineID,
+		&restoredCar.Price,
+		&restoredCar.CreatedAt,
+		&restoredCar.UpdatedAt,
+		&restoredCar.Version,
+	)
+	if err != nil {
+		return restoredCar, fmt.Errorf("failed to restore car: %w", err)
+	}
+
+	if err = audit.Record(ctx, tx, models.AuditRestore, models.AuditEntityCar, restoredCar.ID, nil, restoredCar); err != nil {
+		return restoredCar, err
+	}
+
+	return restoredCar, nil
+}
+
+// shareEngine checks inside tx that an engine is live and holds a share lock
+// on it until tx ends. EngineDelete locks the engine FOR UPDATE, so it cannot
+// delete the engine while a car is being attached to it.
+func shareEngine(ctx context.Context, tx *sql.Tx, engineID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.QueryRowContext(ctx, "SELECT id FROM engine WHERE id = $1 AND deleted_at IS NULL FOR SHARE", engineID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Validation("engine with ID %s does not exist", engineID)
+	} else if err != nil {
+		return fmt.Errorf("failed to verify engine existence: %w", err)
+	}
+	return nil
+}
+
+// shareEngines is shareEngine for many engines at once, failing on the first
+// of ids that is not live.
+func shareEngines(ctx context.Context, tx *sql.Tx, ids []uuid.UUID) error {
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM engine WHERE id = ANY($1) AND deleted_at IS NULL FOR SHARE", pq.Array(idStrings))
+	if err != nil {
+		return fmt.Errorf("failed to verify engine existence: %w", err)
+	}
+	defer rows.Close()
+
+	live := make(map[uuid.UUID]bool, len(ids))
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan engine: %w", err)
+		}
+		live[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	for _, id := range ids {
+		if !live[id] {
+			return models.Validation("engine with ID %s does not exist", id)
+		}
+	}
+	return nil
+}
+
+// lockCar reads a live car's row inside tx and locks it until tx ends.
+func lockCar(ctx context.Context, tx *sql.Tx, id string) (models.Car, error) {
+	var car models.Car
+	query := `
+		SELECT id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at, version
+		FROM car
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&car.ID,
+		&car.Name,
+		&car.Year,
+		&car.Brand,
+		&car.FuelType,
+		&car.Engine.EngineID,
+		&car.Price,
+		&car.CreatedAt,
+		&car.UpdatedAt,
+		&car.Version,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return car, models.NotFound("car with ID %s does not exist", id)
+	} else if err != nil {
+		return car, fmt.Errorf("failed to lock car: %w", err)
+	}
+	return car, nil
+}
+
+// PurgeCars permanently removes cars deleted before the given time, with a
+// purge audit entry holding each car's last state.
+func (s Store) PurgeCars(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	query := `
+		DELETE FROM car WHERE deleted_at < $1
+		RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at, version
+	`
+	rows, err := tx.QueryContext(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge cars: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []audit.Change
+	for rows.Next() {
+		var car models.Car
+		err = rows.Scan(
+			&car.ID,
+			&car.Name,
+			&car.Year,
+			&car.Brand,
+			&car.FuelType,
+			&car.Engine.EngineID,
+			&car.Price,
+			&car.CreatedAt,
+			&car.UpdatedAt,
+			&car.Version,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to scan purged car: %w", err)
+		}
+		changes = append(changes, audit.Change{EntityID: car.ID, Before: car})
+	}
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to purge cars: %w", err)
+	}
+	// The rows must be closed before the connection can run the copy
+	rows.Close()
+
+	if err = audit.RecordAll(ctx, tx, models.AuditPurge, models.AuditEntityCar, changes); err != nil {
+		return 0, err
+	}
+	return int64(len(changes)), nil
+}
+
+// carSortColumns maps the sort fields accepted by models.ParseCarSort to
+// columns. Only these names ever reach the ORDER BY clause.
+var carSortColumns = map[string]string{
+	"name":       "c.name",
+	"year":       "c.year",
+	"brand":      "c.brand",
+	"fuel_type":  "c.fuel_type",
+	"price":      "c.price",
+	"created_at": "c.created_at",
+	"updated_at": "c.updated_at",
+}
+
+func buildCarFilter(filter models.CarFilter) *store.Query {
+	q := &store.Query{}
+	q.AddCondition("c.deleted_at IS NULL")
+
+	if filter.Brand != "" {
+		q.Add("c.brand = $%d", filter.Brand)
+	}
+	if filter.Name != "" {
+		q.Add("c.name ILIKE $%d ESCAPE '\\'", "%"+store.EscapeLike(filter.Name)+"%")
+	}
+	if filter.FuelType != "" {
+		q.Add("c.fuel_type = $%d", filter.FuelType)
+	}
+	if filter.MinYear != 0 {
+		q.Add("c.year >= $%d", fmt.Sprintf("%04d", filter.MinYear))
+	}
+	if filter.MaxYear != 0 {
+		q.Add("c.year <= $%d", fmt.Sprintf("%04d", filter.MaxYear))
+	}
+	if filter.MinPrice != 0 {
+		q.Add("c.price >= $%d", filter.MinPrice)
+	}
+	if filter.MaxPrice != 0 {
+		q.Add("c.price <= $%d", filter.MaxPrice)
+	}
+	if filter.MinDisplacement != 0 {
+		q.Add("e.displacement >= $%d", filter.MinDisplacement)
+	}
+	if filter.MaxDisplacement != 0 {
+		q.Add("e.displacement <= $%d", filter.MaxDisplacement)
+	}
+	if filter.MinCylinders != 0 {
+		q.Add("e.no_of_cylinders >= $%d", filter.MinCylinders)
+	}
+	if filter.MaxCylinders != 0 {
+		q.Add("e.no_of_cylinders <= $%d", filter.MaxCylinders)
+	}
+	if filter.MinRange != 0 {
+		q.Add("e.car_range >= $%d", filter.MinRange)
+	}
+	if filter.MaxRange != 0 {
+		q.Add("e.car_range <= $%d", filter.MaxRange)
+	}
+
+	return q
+}
+
+func (s Store) ListCars(ctx context.Context, filter models.CarFilter) (models.CarPage, error) {
+	page := models.CarPage{Cars: []models.Car{}}
+	from := ` FROM car c JOIN engine e ON c.engine_id = e.id`
+
+	// Total count ignores the cursor so it stays the same across pages
+	q := buildCarFilter(filter)
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*)"+from+q.Where(), q.Args...).Scan(&page.TotalCount)
+	if err != nil {
+		return page, fmt.Errorf("failed to count cars: %w", err)
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := models.DecodeCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return page, err
+		}
+		q.AddCursor(cursor, filter.Sort, carSortColumns, "c.id")
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	limit := q.Placeholder(filter.Limit + 1)
+	query := `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.engine_id, c.price, c.created_at, c.updated_at, c.version, e.id, e.displacement, e.no_of_cylinders, e.car_range` +
+		from + q.Where() + store.OrderBy(filter.Sort, carSortColumns, "c.id") + fmt.Sprintf(" LIMIT $%d", limit)
+
+	rows, err := s.db.QueryContext(ctx, query, q.Args...)
+	if err != nil {
+		return page, fmt.Errorf("failed to fetch cars: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var car models.Car
+		err := rows.Scan(
+			&car.ID,
+			&car.Name,
+			&car.Year,
+			&car.Brand,
+			&car.FuelType,
+			&car.Engine.EngineID,
+			&car.Price,
+			&car.CreatedAt,
+			&car.UpdatedAt,
+			&car.Version,
+			&car.Engine.EngineID,
+			&car.Engine.Displacement,
+			&car.Engine.NoOfCylinders,
+			&car.Engine.CarRange,
+		)
+		if err != nil {
+			return page, fmt.Errorf("failed to scan car with engine: %w", err)
+		}
+		page.Cars = append(page.Cars, car)
+	}
+	if err = rows.Err(); err != nil {
+		return page, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if len(page.Cars) > filter.Limit {
+		page.Cars = page.Cars[:filter.Limit]
+		page.NextCursor = models.CarCursor(page.Cars[filter.Limit-1], filter.Sort)
+	}
+	return page, nil
+}
+
+// ExportCars streams every car matching the filter to fn, straight from the
+// rows cursor, so an export never holds the whole result in memory. Limit
+// and Cursor are ignored.
+func (s Store) ExportCars(ctx context.Context, filter models.CarFilter, fn func(models.Car) error) error {
+	q := buildCarFilter(filter)
+	query := `SELECT c.id, c.name, c.year, c.brand, c.fuel_type, c.engine_id, c.price, c.created_at, c.updated_at, c.version, e.id, e.displacement, e.no_of_cylinders, e.car_range` +
+		` FROM car c JOIN engine e ON c.engine_id = e.id` + q.Where() + store.OrderBy(filter.Sort, carSortColumns, "c.id")
+
+	rows, err := s.db.QueryContext(ctx, query, q.Args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch cars: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var car models.Car
+		err := rows.Scan(
+			&car.ID,
+			&car.Name,
+			&car.Year,
+			&car.Brand,
+			&car.FuelType,
+			&car.Engine.EngineID,
+			&car.Price,
+			&car.CreatedAt,
+			&car.UpdatedAt,
+			&car.Version,
+			&car.Engine.EngineID,
+			&car.Engine.Displacement,
+			&car.Engine.NoOfCylinders,
+			&car.Engine.CarRange,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to scan car with engine: %w", err)
+		}
+		if err := fn(car); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("rows iteration error: %w", err)
+	}
+	return nil
+}
+
+// GetCarsByEngine lists every car using the engine, so callers can check
+// what an engine deletion would affect.
+func (s Store) GetCarsByEngine(ctx context.Context, engineID string) ([]models.Car, error) {
+	cars := []models.Car{}
+
+	var engineExists bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM engine WHERE id = $1 AND deleted_at IS NULL)", engineID).Scan(&engineExists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify engine existence: %w", err)
+	}
+	if !engineExists {
+		return nil, models.NotFound("engine with ID %s does not exist", engineID)
+	}
+
+	query := `
+		SELECT
+			c.id, c.name, c.year, c.brand, c.fuel_type, c.engine_id, c.price, c.created_at, c.updated_at, c.version,
+			e.id, e.displacement, e.no_of_cylinders, e.car_range
+		FROM car c
+		JOIN engine e ON c.engine_id = e.id
+		WHERE c.engine_id = $1 AND c.deleted_at IS NULL
+		ORDER BY c.created_at, c.id
+	`
+	rows, err := s.db.QueryContext(ctx, query, engineID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cars: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var car models.Car
+		err := rows.Scan(
+			&car.ID,
+			&car.Name,
+			&car.Year,
+			&car.Brand,
+			&car.FuelType,
+			&car.Engine.EngineID,
+			&car.Price,
+			&car.CreatedAt,
+			&car.UpdatedAt,
+			&car.Version,
+			&car.Engine.EngineID,
+			&car.Engine.Displacement,
+			&car.Engine.NoOfCylinders,
+			&car.Engine.CarRange,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan car with engine: %w", err)
+		}
+		cars = append(cars, car)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return cars, nil
+}
+
+// GetEnginesByIDs returns the live engines among ids keyed by ID, so a bulk
+// import checks every engine it references with one query.
+func (s Store) GetEnginesByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.Engine, error) {
+	engines := make(map[uuid.UUID]models.Engine, len(ids))
+	if len(ids) == 0 {
+		return engines, nil
+	}
+
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = id.String()
+	}
+
+	query := `
+		SELECT id, displacement, no_of_cylinders, car_range, version
+		FROM engine
+		WHERE id = ANY($1) AND deleted_at IS NULL
+	`
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(idStrings))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch engines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var engine models.Engine
+		err := rows.Scan(
+			&engine.EngineID,
+			&engine.Displacement,
+			&engine.NoOfCylinders,
+			&engine.CarRange,
+			&engine.Version,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan engine: %w", err)
+		}
+		engines[engine.EngineID] = engine
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return engines, nil
+}
+
+// ImportCars inserts already validated cars with COPY in one transaction,
+// auditing each, and returns them in the same order. The engines are checked
+// again under a share lock, since one may have been deleted after the
+// service validated the rows.
+func (s Store) ImportCars(ctx context.Context, carReqs []models.CarRequest) (createdCars []models.Car, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	engineIDs := make([]uuid.UUID, 0, len(carReqs))
+	for _, carReq := range carReqs {
+		engineIDs = append(engineIDs, carReq.Engine.EngineID)
+	}
+	if err = shareEngines(ctx, tx, engineIDs); err != nil {
+		return nil, err
+	}
+
+	// Postgres keeps microseconds; truncate so the returned cars match what a read returns
+	currentTime := time.Now().Truncate(time.Microsecond)
+
+	createdCars = make([]models.Car, 0, len(carReqs))
+	rows := make([][]interface{}, 0, len(carReqs))
+	changes := make([]audit.Change, 0, len(carReqs))
+	for _, carReq := range carReqs {
+		car := models.Car{
+			ID:        uuid.New(),
+			Name:      carReq.Name,
+			Year:      carReq.Year,
+			Brand:     carReq.Brand,
+			FuelType:  carReq.FuelType,
+			Engine:    models.Engine{EngineID: carReq.Engine.EngineID},
+			Price:     carReq.Price,
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+			Version:   1,
+		}
+		createdCars = append(createdCars, car)
+		rows = append(rows, []interface{}{
+			car.ID.String(), car.Name, car.Year, car.Brand, car.FuelType, car.Engine.EngineID.String(), car.Price, car.CreatedAt, car.UpdatedAt,
+		})
+		changes = append(changes, audit.Change{EntityID: car.ID, After: car})
+	}
+
+	columns := []string{"id", "name", "year", "brand", "fuel_type", "engine_id", "price", "created_at", "updated_at"}
+	if err = store.CopyIn(ctx, tx, "car", columns, rows); err != nil {
+		return nil, err
+	}
+	if err = audit.RecordAll(ctx, tx, models.AuditCreate, models.AuditEntityCar, changes); err != nil {
+		return nil, err
+	}
+
+	return createdCars, nil
+}
+
+// CountInventory counts live cars by brand and fuel type.
+func (s Store) CountInventory(ctx context.Context) ([]models.InventoryCount, error) {
+	counts := []models.InventoryCount{}
+
+	query := `
+		SELECT brand, fuel_type, COUNT(*)
+		FROM car
+		WHERE deleted_at IS NULL
+		GROUP BY brand, fuel_type
+		ORDER BY brand, fuel_type
+	`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count cars: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var count models.InventoryCount
+		if err := rows.Scan(&count.Brand, &count.FuelType, &count.Cars); err != nil {
+			return nil, fmt.Errorf("failed to scan car count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,395 @@
+//go:build integration
+
+package car
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	auditStore "github.com/MarNawar/carZone/store/audit"
+	engineStore "github.com/MarNawar/carZone/store/engine"
+	"github.com/MarNawar/carZone/store/storetest"
+	"github.com/google/uuid"
+)
+
+func newStores(t *testing.T) (Store, *engineStore.EngineStore) {
+	t.Helper()
+	db := storetest.NewDB(t)
+	return New(db), engineStore.New(db)
+}
+
+func createEngine(t *testing.T, engines *engineStore.EngineStore) models.Engine {
+	t.Helper()
+	engine, err := engines.CreateEngine(context.Background(), &models.EngineRequest{
+		Displacement:  2000,
+		NoOfCylinders: 4,
+		CarRange:      600,
+	})
+	if err != nil {
+		t.Fatalf("CreateEngine: %v", err)
+	}
+	return engine
+}
+
+func createCar(t *testing.T, s Store, name, brand string, price float64, engine models.Engine) models.Car {
+	t.Helper()
+	car, err := s.CreateCar(context.Background(), &models.CarRequest{
+		Name:     name,
+		Year:     "2020",
+		Brand:    brand,
+		FuelType: "Petrol",
+		Engine:   engine,
+		Price:    price,
+	})
+	if err != nil {
+		t.Fatalf("CreateCar: %v", err)
+	}
+	return car
+}
+
+func TestCarCRUD(t *testing.T) {
+	s, engines := newStores(t)
+	ctx := context.Background()
+	engine := createEngine(t, engines)
+
+	created := createCar(t, s, "Corolla", "Toyota", 20000, engine)
+	if created.ID == uuid.Nil || created.Engine.EngineID != engine.EngineID {
+		t.Fatalf("CreateCar returned %+v", created)
+	}
+
+	got, err := s.GetCarById(ctx, created.ID.String())
+	if err != nil {
+		t.Fatalf("GetCarById: %v", err)
+	}
+	if got.Name != "Corolla" || got.Engine != engine {
+		t.Fatalf("GetCarById = %+v, want name Corolla with engine %+v", got, engine)
+	}
+
+	updated, err := s.UpdateCar(ctx, created.ID.String(), &models.CarRequest{Name: "Corolla", Year: "2021", Brand: "Toyota", FuelType: "Petrol", Engine: engine, Price: 21000}, created.Version)
+	if err != nil {
+		t.Fatalf("UpdateCar: %v", err)
+	}
+	if updated.Price != 21000 || updated.Year != "2021" || updated.Name != "Corolla" {
+		t.Fatalf("UpdateCar = %+v", updated)
+	}
+
+	if _, err := s.DeleteCar(ctx, created.ID.String()); err != nil {
+		t.Fatalf("DeleteCar: %v", err)
+	}
+	if _, err := s.GetCarById(ctx, created.ID.String()); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("GetCarById after delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestCarErrors(t *testing.T) {
+	s, _ := newStores(t)
+	ctx := context.Background()
+	missing := uuid.NewString()
+
+	if _, err := s.GetCarById(ctx, missing); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetCarById: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.DeleteCar(ctx, missing); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("DeleteCar: got %v, want ErrNotFound", err)
+	}
+
+	_, err := s.CreateCar(ctx, &models.CarRequest{
+		Name:     "Ghost",
+		Year:     "2020",
+		Brand:    "Toyota",
+		FuelType: "Petrol",
+		Engine:   models.Engine{EngineID: uuid.New()},
+		Price:    1,
+	})
+	if !errors.Is(err, models.ErrValidation) {
+		t.Errorf("CreateCar with unknown engine: got %v, want ErrValidation", err)
+	}
+}
+
+func TestGetCarByBrandAndEngine(t *testing.T) {
+	s, engines := newStores(t)
+	ctx := context.Background()
+	engine := createEngine(t, engines)
+	other := createEngine(t, engines)
+
+	createCar(t, s, "Corolla", "Toyota", 20000, engine)
+	createCar(t, s, "Camry", "Toyota", 30000, other)
+	createCar(t, s, "Civic", "Honda", 25000, engine)
+
+	cars, err := s.GetCarByBrand(ctx, "Toyota", true)
+	if err != nil {
+		t.Fatalf("GetCarByBrand: %v", err)
+	}
+	if len(cars) != 2 {
+		t.Fatalf("GetCarByBrand returned %d cars, want 2", len(cars))
+	}
+
+	byEngine, err := s.GetCarsByEngine(ctx, engine.EngineID.String())
+	if err != nil {
+		t.Fatalf("GetCarsByEngine: %v", err)
+	}
+	if len(byEngine) != 2 || byEngine[0].Name != "Corolla" || byEngine[1].Name != "Civic" {
+		t.Fatalf("GetCarsByEngine = %+v", byEngine)
+	}
+
+	if _, err := s.GetCarsByEngine(ctx, uuid.NewString()); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("GetCarsByEngine for unknown engine: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestListCars(t *testing.T) {
+	s, engines := newStores(t)
+	ctx := context.Background()
+	engine := createEngine(t, engines)
+
+	for i, price := range []float64{15000, 40000, 25000, 30000} {
+		createCar(t, s, "Model "+string(rune('A'+i)), "Toyota", price, engine)
+	}
+	createCar(t, s, "Civic", "Honda", 22000, engine)
+
+	filter := models.CarFilter{
+		Brand:    "Toyota",
+		MinPrice: 20000,
+		Sort:     []models.SortField{{Field: "price"}},
+		Limit:    2,
+	}
+	page, err := s.ListCars(ctx, filter)
+	if err != nil {
+		t.Fatalf("ListCars: %v", err)
+	}
+	if page.TotalCount != 3 || len(page.Cars) != 2 || page.NextCursor == "" {
+		t.Fatalf("first page: total %d, %d cars, cursor %q", page.TotalCount, len(page.Cars), page.NextCursor)
+	}
+
+	filter.Cursor = page.NextCursor
+	next, err := s.ListCars(ctx, filter)
+	if err != nil {
+		t.Fatalf("ListCars with cursor: %v", err)
+	}
+	if len(next.Cars) != 1 || next.NextCursor != "" {
+		t.Fatalf("second page: %d cars, cursor %q", len(next.Cars), next.NextCursor)
+	}
+
+	got := []float64{page.Cars[0].Price, page.Cars[1].Price, next.Cars[0].Price}
+	want := []float64{25000, 30000, 40000}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("prices = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestExportCars(t *testing.T) {
+	s, engines := newStores(t)
+	ctx := context.Background()
+	engine := createEngine(t, engines)
+
+	for i, price := range []float64{15000, 40000, 25000} {
+		createCar(t, s, "Model "+string(rune('A'+i)), "Toyota", price, engine)
+	}
+	createCar(t, s, "Civic", "Honda", 22000, engine)
+
+	filter := models.CarFilter{Brand: "Toyota", Sort: []models.SortField{{Field: "price", Desc: true}}}
+	var got []float64
+	err := s.ExportCars(ctx, filter, func(car models.Car) error {
+		if car.Engine != engine {
+			t.Errorf("exported engine = %+v, want %+v", car.Engine, engine)
+		}
+		got = append(got, car.Price)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ExportCars: %v", err)
+	}
+	want := []float64{40000, 25000, 15000}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("exported prices = %v, want %v", got, want)
+	}
+
+	stop := errors.New("stop")
+	err = s.ExportCars(ctx, filter, func(models.Car) error { return stop })
+	if !errors.Is(err, stop) {
+		t.Fatalf("ExportCars with failing callback: got %v, want %v", err, stop)
+	}
+}
+
+func TestEngineDeletePolicy(t *testing.T) {
+	s, engines := newStores(t)
+	ctx := context.Background()
+	engine := createEngine(t, engines)
+	spare := createEngine(t, engines)
+	car := createCar(t, s, "Corolla", "Toyota", 20000, engine)
+
+	_, err := engines.EngineDelete(ctx, engine.EngineID.String(), models.EngineDeleteOptions{})
+	var appErr *models.Error
+	if !errors.As(err, &appErr) || !errors.Is(err, models.ErrConflict) {
+		t.Fatalf("EngineDelete with dependents: got %v, want ErrConflict", err)
+	}
+	details, ok := appErr.Details.(models.EngineInUseDetails)
+	if !ok || len(details.CarIDs) != 1 || details.CarIDs[0] != car.ID {
+		t.Fatalf("conflict details = %+v, want car %s", appErr.Details, car.ID)
+	}
+
+	_, err = engines.EngineDelete(ctx, engine.EngineID.String(), models.EngineDeleteOptions{ReassignTo: spare.EngineID.String()})
+	if err != nil {
+		t.Fatalf("EngineDelete with reassign_to: %v", err)
+	}
+	moved, err := s.GetCarById(ctx, car.ID.String())
+	if err != nil {
+		t.Fatalf("GetCarById after reassign: %v", err)
+	}
+	if moved.Engine.EngineID != spare.EngineID {
+		t.Fatalf("car engine = %s, want %s", moved.Engine.EngineID, spare.EngineID)
+	}
+
+	_, err = engines.EngineDelete(ctx, spare.EngineID.String(), models.EngineDeleteOptions{Cascade: true})
+	if err != nil {
+		t.Fatalf("EngineDelete with cascade: %v", err)
+	}
+	if _, err := s.GetCarById(ctx, car.ID.String()); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("GetCarById after cascade: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestSoftDeleteRestoreAndPurge(t *testing.T) {
+	s, engines := newStores(t)
+	ctx := context.Background()
+	engine := createEngine(t, engines)
+	car := createCar(t, s, "Corolla", "Toyota", 20000, engine)
+
+	if _, err := engines.EngineDelete(ctx, engine.EngineID.String(), models.EngineDeleteOptions{Cascade: true}); err != nil {
+		t.Fatalf("EngineDelete with cascade: %v", err)
+	}
+	if cars, err := s.GetCarByBrand(ctx, "Toyota", false); err != nil || len(cars) != 0 {
+		t.Fatalf("GetCarByBrand after delete = %v, %v; want no cars", cars, err)
+	}
+
+	if _, err := s.RestoreCar(ctx, car.ID.String()); !errors.Is(err, models.ErrConflict) {
+		t.Fatalf("RestoreCar with deleted engine: got %v, want ErrConflict", err)
+	}
+	if _, err := engines.RestoreEngine(ctx, engine.EngineID.String()); err != nil {
+		t.Fatalf("RestoreEngine: %v", err)
+	}
+	if _, err := s.RestoreCar(ctx, car.ID.String()); err != nil {
+		t.Fatalf("RestoreCar: %v", err)
+	}
+	if _, err := s.GetCarById(ctx, car.ID.String()); err != nil {
+		t.Fatalf("GetCarById after restore: %v", err)
+	}
+
+	if _, err := s.DeleteCar(ctx, car.ID.String()); err != nil {
+		t.Fatalf("DeleteCar: %v", err)
+	}
+	purged, err := s.PurgeCars(ctx, time.Now().Add(time.Minute))
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeCars = %d, %v; want 1", purged, err)
+	}
+	if _, err := s.RestoreCar(ctx, car.ID.String()); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("RestoreCar after purge: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestMutationsAreAudited(t *testing.T) {
+	db := storetest.NewDB(t)
+	s, engines, auditLog := New(db), engineStore.New(db), auditStore.New(db)
+	ctx := models.WithActor(context.Background(), "alice")
+
+	engine, err := engines.CreateEngine(ctx, &models.EngineRequest{Displacement: 2000, NoOfCylinders: 4, CarRange: 600})
+	if err != nil {
+		t.Fatalf("CreateEngine: %v", err)
+	}
+	car, err := s.CreateCar(ctx, &models.CarRequest{Name: "Corolla", Year: "2020", Brand: "Toyota", FuelType: "Petrol", Engine: engine, Price: 20000})
+	if err != nil {
+		t.Fatalf("CreateCar: %v", err)
+	}
+	if _, err := s.UpdateCar(ctx, car.ID.String(), &models.CarRequest{Name: "Corolla", Year: "2020", Brand: "Toyota", FuelType: "Petrol", Engine: engine, Price: 21000}, 0); err != nil {
+		t.Fatalf("UpdateCar: %v", err)
+	}
+	if _, err := engines.EngineDelete(ctx, engine.EngineID.String(), models.EngineDeleteOptions{Cascade: true}); err != nil {
+		t.Fatalf("EngineDelete: %v", err)
+	}
+
+	entries, err := auditLog.ListAudit(context.Background(), models.AuditFilter{
+		Entity:   models.AuditEntityCar,
+		EntityID: car.ID.String(),
+		Limit:    models.DefaultAuditLimit,
+	})
+	if err != nil {
+		t.Fatalf("ListAudit: %v", err)
+	}
+
+	want := []models.AuditAction{models.AuditDelete, models.AuditUpdate, models.AuditCreate}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d audit entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.Action != want[i] || entry.Actor != "alice" {
+			t.Errorf("entry %d = %s by %s, want %s by alice", i, entry.Action, entry.Actor, want[i])
+		}
+	}
+	if entries[0].Before == nil || entries[0].After != nil {
+		t.Errorf("delete entry should have only a before state: %+v", entries[0])
+	}
+}
+
+func TestImportCars(t *testing.T) {
+	db := storetest.NewDB(t)
+	s, engines, auditLog := New(db), engineStore.New(db), auditStore.New(db)
+	ctx := context.Background()
+	engine := createEngine(t, engines)
+
+	found, err := s.GetEnginesByIDs(ctx, []uuid.UUID{engine.EngineID, uuid.New()})
+	if err != nil {
+		t.Fatalf("GetEnginesByIDs: %v", err)
+	}
+	if len(found) != 1 || found[engine.EngineID] != engine {
+		t.Fatalf("GetEnginesByIDs = %+v, want only %+v", found, engine)
+	}
+
+	// More rows than one COPY batch
+	carReqs := make([]models.CarRequest, 1500)
+	for i := range carReqs {
+		carReqs[i] = models.CarRequest{Name: "Corolla", Year: "2020", Brand: "Toyota", FuelType: "Petrol", Engine: engine, Price: float64(20000 + i)}
+	}
+	imported, err := s.ImportCars(ctx, carReqs)
+	if err != nil {
+		t.Fatalf("ImportCars: %v", err)
+	}
+	if len(imported) != len(carReqs) {
+		t.Fatalf("ImportCars returned %d cars, want %d", len(imported), len(carReqs))
+	}
+
+	last := imported[len(imported)-1]
+	got, err := s.GetCarById(ctx, last.ID.String())
+	if err != nil {
+		t.Fatalf("GetCarById: %v", err)
+	}
+	if got.Price != 21499 || got.Version != 1 {
+		t.Fatalf("GetCarById = %+v, want price 21499 at version 1", got)
+	}
+
+	entries, err := auditLog.ListAudit(ctx, models.AuditFilter{Entity: models.AuditEntityCar, EntityID: last.ID.String(), Limit: models.DefaultAuditLimit})
+	if err != nil {
+		t.Fatalf("ListAudit: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Action != models.AuditCreate {
+		t.Fatalf("ListAudit = %+v, want one create entry", entries)
+	}
+
+	// A missing engine fails the foreign key and rolls back the whole import
+	carReqs = []models.CarRequest{carReqs[0], carReqs[1]}
+	carReqs[1].Engine = models.Engine{EngineID: uuid.New()}
+	if _, err := s.ImportCars(ctx, carReqs); err == nil {
+		t.Fatal("ImportCars with a missing engine: got nil error")
+	}
+	page, err := s.ListCars(ctx, models.CarFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("ListCars: %v", err)
+	}
+	if page.TotalCount != 1500 {
+		t.Fatalf("ListCars total = %d after failed import, want 1500", page.TotalCount)
+	}
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/lib/pq"
+)
+
+// CopyBatchSize is how many rows go into one COPY statement.
+const CopyBatchSize = 1000
+
+// CopyIn inserts rows into table with COPY inside tx, CopyBatchSize rows per
+// statement, so bulk inserts cost one round trip per batch instead of per row.
+func CopyIn(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]interface{}) error {
+	for start := 0; start < len(rows); start += CopyBatchSize {
+		if err := copyBatch(ctx, tx, table, columns, rows[start:min(start+CopyBatchSize, len(rows))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyBatch(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]interface{}) (err error) {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return fmt.Errorf("failed to start copy into %s: %w", table, err)
+	}
+	defer func() {
+		if closeErr := stmt.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to copy into %s: %w", table, closeErr)
+		}
+	}()
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("failed to copy into %s: %w", table, err)
+		}
+	}
+	// An empty Exec flushes the buffered rows
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to copy into %s: %w", table, err)
+	}
+	slog.DebugContext(ctx, "Copied rows", "table", table, "rows", len(rows))
+	return nil
+}
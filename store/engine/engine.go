@@ -5,9 +5,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store"
+	"github.com/MarNawar/carZone/store/audit"
 	"github.com/google/uuid"
 )
 
@@ -24,9 +26,9 @@ func (e EngineStore) EngineById(ctx context.Context, id string) (models.Engine,
 
 	query := `
 		SELECT 
-			engine_id, displacement, no_of_cylinders, car_range
+			id, displacement, no_of_cylinders, car_range, version
 		FROM engine
-		WHERE engine_id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	// Use QueryRowContext for single row retrieval
@@ -35,12 +37,13 @@ func (e EngineStore) EngineById(ctx context.Context, id string) (models.Engine,
 		&engine.Displacement,
 		&engine.NoOfCylinders,
 		&engine.CarRange,
+		&engine.Version,
 	)
 
 	// Handle errors
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return engine, fmt.Errorf("engine with ID %s does not exist", id)
+			return engine, models.NotFound("engine with ID %s does not exist", id)
 		}
 		return engine, fmt.Errorf("failed to fetch engine: %w", err)
 	}
@@ -48,8 +51,7 @@ func (e EngineStore) EngineById(ctx context.Context, id string) (models.Engine,
 	return engine, nil
 }
 
-func (e EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
-	var createdEngine models.Engine
+func (e EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (createdEngine models.Engine, err error) {
 	engineID := uuid.New()
 
 	newEngine := models.Engine{
@@ -75,9 +77,9 @@ func (e EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineR
 
 	// Insert engine into database
 	query := `
-			INSERT INTO engine (engine_id, displacement, noOfCylinders, carRange) 
-			VALUES ($1, $2, $3, $4) 
-			RETURNING engine_id, displacement, noOfCylinders, carRange
+			INSERT INTO engine (id, displacement, no_of_cylinders, car_range)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, displacement, no_of_cylinders, car_range, version
 		`
 
 	err = tx.QueryRowContext(
@@ -92,53 +94,31 @@ func (e EngineStore) CreateEngine(ctx context.Context, engineReq *models.EngineR
 		&createdEngine.Displacement,
 		&createdEngine.NoOfCylinders,
 		&createdEngine.CarRange,
+		&createdEngine.Version,
 	)
 
 	if err != nil {
 		return newEngine, fmt.Errorf("failed to create engine:  %w", err)
 	}
 
-	return createdEngine, nil
-}
-
-func (e EngineStore) EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest) (models.Engine, error) {
-	var updatedEngine models.Engine
-
-	// Fetch existing car to validate ID
-	var exists bool
-	err := e.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM engine WHERE engine_id = $1)", id).Scan(&exists)
-	if err != nil {
-		return updatedEngine, fmt.Errorf("failed to check engine existence: %w", err)
-	}
-	if !exists {
-		return updatedEngine, fmt.Errorf("engine with ID %s does not exist", id)
+	if err = audit.Record(ctx, tx, models.AuditCreate, models.AuditEntityEngine, createdEngine.EngineID, nil, createdEngine); err != nil {
+		return createdEngine, err
 	}
 
-	// Start building the dynamic query
-	var queryBuilder strings.Builder
-	queryBuilder.WriteString("UPDATE engine SET ")
-	var args []interface{}
-	argID := 1
-
-	if engineReq.Displacement != 0 {
-		queryBuilder.WriteString(fmt.Sprintf("displacement = $%d, ", argID))
-		args = append(args, engineReq.Displacement)
-		argID++
-	}
-	if engineReq.NoOfCylinders != 0 {
-		queryBuilder.WriteString(fmt.Sprintf("noOfCylinders = $%d, ", argID))
-		args = append(args, engineReq.NoOfCylinders)
-		argID++
-	}
-	if engineReq.CarRange != 0 {
-		queryBuilder.WriteString(fmt.Sprintf("carRange = $%d, ", argID))
-		args = append(args, engineReq.CarRange)
-		argID++
-	}
+	return createdEngine, nil
+}
 
-	// Add the WHERE clause
-	queryBuilder.WriteString(fmt.Sprintf(" WHERE engine_id = $%d RETURNING engine_id, displacement, noOfCylinders, carRange", argID))
-	args = append(args, id)
+// EngineUpdate replaces every field of the engine with engineReq. A
+// non-zero expectedVersion must match the engine's version, checked under
+// the row lock.
+func (e EngineStore) EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest, expectedVersion int64) (updatedEngine models.Engine, err error) {
+	// Every field is replaced; partial updates go through PatchEngine in the service
+	query := `
+		UPDATE engine
+		SET displacement = $1, no_of_cylinders = $2, car_range = $3, updated_at = $4, version = version + 1
+		WHERE id = $5 AND deleted_at IS NULL
+		RETURNING id, displacement, no_of_cylinders, car_range, version`
+	args := []interface{}{engineReq.Displacement, engineReq.NoOfCylinders, engineReq.CarRange, time.Now(), id}
 
 	// Begin transaction
 	tx, err := e.db.BeginTx(ctx, nil)
@@ -147,31 +127,46 @@ func (e EngineStore) EngineUpdate(ctx context.Context, id string, engineReq *mod
 	}
 	defer func() {
 		if err != nil {
-			tx.Rollback()
+			_ = tx.Rollback()
 			return
 		}
 		err = tx.Commit()
 	}()
 
+	// Lock the row and keep its current state for the audit log
+	before, err := lockEngine(ctx, tx, id)
+	if err != nil {
+		return updatedEngine, err
+	}
+	if expectedVersion != 0 && before.Version != expectedVersion {
+		return updatedEngine, models.StaleVersion("engine", id, expectedVersion, before.Version)
+	}
+
 	// Execute the query
-	err = tx.QueryRowContext(ctx, queryBuilder.String(), args...).
+	err = tx.QueryRowContext(ctx, query, args...).
 		Scan(
 			&updatedEngine.EngineID,
 			&updatedEngine.Displacement,
 			&updatedEngine.NoOfCylinders,
 			&updatedEngine.CarRange,
+			&updatedEngine.Version,
 		)
 
 	if err != nil {
 		return updatedEngine, fmt.Errorf("failed to update engine: %w", err)
 	}
 
+	if err = audit.Record(ctx, tx, models.AuditUpdate, models.AuditEntityEngine, updatedEngine.EngineID, before, updatedEngine); err != nil {
+		return updatedEngine, err
+	}
+
 	return updatedEngine, nil
 }
 
-func (e EngineStore) EngineDelete(ctx context.Context, id string) (models.Engine, error) {
-	var deletedEngine models.Engine
-
+// EngineDelete soft deletes the engine and, in the same transaction, deals
+// with the cars using it as options says: deletes them, moves them to
+// another engine, or by default refuses with a conflict listing their IDs.
+func (e EngineStore) EngineDelete(ctx context.Context, id string, options models.EngineDeleteOptions) (deletedEngine models.Engine, err error) {
 	// Begin transaction
 	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -186,25 +181,406 @@ func (e EngineStore) EngineDelete(ctx context.Context, id string) (models.Engine
 		err = tx.Commit()
 	}()
 
-	// Delete and return the engine details
+	now := time.Now()
+
+	// Lock the engine so no car can be attached to it while we decide
+	before, err := lockEngine(ctx, tx, id)
+	if err != nil {
+		return deletedEngine, err
+	}
+
+	switch {
+	case options.ReassignTo != "":
+		var targetExists bool
+		err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM engine WHERE id = $1 AND deleted_at IS NULL FOR SHARE)", options.ReassignTo).Scan(&targetExists)
+		if err != nil {
+			return deletedEngine, fmt.Errorf("failed to verify engine existence: %w", err)
+		}
+		if !targetExists {
+			return deletedEngine, models.Validation("engine with ID %s does not exist", options.ReassignTo)
+		}
+		query := `
+			WITH previous AS (
+				SELECT id, engine_id, updated_at FROM car
+				WHERE engine_id = $3 AND deleted_at IS NULL
+				FOR UPDATE
+			)
+			UPDATE car c SET engine_id = $1, updated_at = $2, version = c.version + 1
+			FROM previous p
+			WHERE c.id = p.id
+			RETURNING c.id, c.name, c.year, c.brand, c.fuel_type, c.engine_id, c.price, c.created_at, c.updated_at, c.version,
+				p.engine_id, p.updated_at
+		`
+		if err = auditCarChanges(ctx, tx, models.AuditUpdate, query, options.ReassignTo, now, id); err != nil {
+			return deletedEngine, fmt.Errorf("failed to reassign cars: %w", err)
+		}
+	case options.Cascade:
+		query := `
+			UPDATE car SET deleted_at = $1, version = version + 1
+			WHERE engine_id = $2 AND deleted_at IS NULL
+			RETURNING id, name, year, brand, fuel_type, engine_id, price, created_at, updated_at, version,
+				engine_id, updated_at
+		`
+		if err = auditCarChanges(ctx, tx, models.AuditDelete, query, now, id); err != nil {
+			return deletedEngine, fmt.Errorf("failed to delete cars: %w", err)
+		}
+	default:
+		var carIDs []uuid.UUID
+		carIDs, err = carsUsingEngine(ctx, tx, id)
+		if err != nil {
+			return deletedEngine, err
+		}
+		if len(carIDs) > 0 {
+			return deletedEngine, models.EngineInUse(id, carIDs)
+		}
+	}
+
+	// Soft delete and return the engine details
 	query := `
-		DELETE FROM engine 
-		WHERE engine_id = $1 
-		RETURNING engine_id, displacement, noOfCylinders, carRange
+		UPDATE engine SET deleted_at = $2, version = version + 1
+		WHERE id = $1
+		RETURNING id, displacement, no_of_cylinders, car_range, version
 	`
-	err = tx.QueryRowContext(ctx, query, id).Scan(
+	err = tx.QueryRowContext(ctx, query, id, now).Scan(
 		&deletedEngine.EngineID,
 		&deletedEngine.Displacement,
 		&deletedEngine.NoOfCylinders,
 		&deletedEngine.CarRange,
+		&deletedEngine.Version,
 	)
-
-	// Handle error when no rows are affected
-	if err == sql.ErrNoRows {
-		return deletedEngine, fmt.Errorf("engine with ID %s does not exist", id)
-	} else if err != nil {
+	if err != nil {
 		return deletedEngine, fmt.Errorf("failed to delete engine: %w", err)
 	}
 
+	if err = audit.Record(ctx, tx, models.AuditDelete, models.AuditEntityEngine, deletedEngine.EngineID, before, nil); err != nil {
+		return deletedEngine, err
+	}
+
 	return deletedEngine, nil
 }
+
+// RestoreEngine undoes EngineDelete. Cars deleted along with the engine stay
+// deleted and are restored one by one.
+func (e EngineStore) RestoreEngine(ctx context.Context, id string) (restoredEngine models.Engine, err error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return restoredEngine, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	query := `
+		UPDATE engine SET deleted_at = NULL, updated_at = $2, version = version + 1
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING id, displacement, no_of_cylinders, car_range, version
+	`
+	err = tx.QueryRowContext(ctx, query, id, time.Now()).Scan(
+		&restoredEngine.EngineID,
+		&restoredEngine.Displacement,
+		&restoredEngine.NoOfCylinders,
+		&restoredEngine.CarRange,
+		&restoredEngine.Version,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM engine WHERE id = $1)", id).Scan(&exists); err != nil {
+			return restoredEngine, fmt.Errorf("failed to check engine existence: %w", err)
+		}
+		if exists {
+			return restoredEngine, models.Conflict("engine with ID %s is not deleted", id)
+		}
+		return restoredEngine, models.NotFound("engine with ID %s does not exist", id)
+	} else if err != nil {
+		return restoredEngine, fmt.Errorf("failed to restore engine: %w", err)
+	}
+
+	if err = audit.Record(ctx, tx, models.AuditRestore, models.AuditEntityEngine, restoredEngine.EngineID, nil, restoredEngine); err != nil {
+		return restoredEngine, err
+	}
+
+	return restoredEngine, nil
+}
+
+// PurgeEngines permanently removes engines deleted before the given time,
+// with a purge audit entry holding each engine's last state. Engines still
+// referenced by a car, deleted or not, are kept; purge cars first.
+func (e EngineStore) PurgeEngines(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	query := `
+		DELETE FROM engine e
+		WHERE e.deleted_at < $1
+		AND NOT EXISTS (SELECT 1 FROM car c WHERE c.engine_id = e.id)
+		RETURNING e.id, e.displacement, e.no_of_cylinders, e.car_range, e.version
+	`
+	rows, err := tx.QueryContext(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge engines: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []audit.Change
+	for rows.Next() {
+		var engine models.Engine
+		err = rows.Scan(
+			&engine.EngineID,
+			&engine.Displacement,
+			&engine.NoOfCylinders,
+			&engine.CarRange,
+			&engine.Version,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to scan purged engine: %w", err)
+		}
+		changes = append(changes, audit.Change{EntityID: engine.EngineID, Before: engine})
+	}
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to purge engines: %w", err)
+	}
+	// The rows must be closed before the connection can run the copy
+	rows.Close()
+
+	if err = audit.RecordAll(ctx, tx, models.AuditPurge, models.AuditEntityEngine, changes); err != nil {
+		return 0, err
+	}
+	return int64(len(changes)), nil
+}
+
+// lockEngine reads a live engine's row inside tx and locks it until tx ends.
+func lockEngine(ctx context.Context, tx *sql.Tx, id string) (models.Engine, error) {
+	var engine models.Engine
+	query := `
+		SELECT id, displacement, no_of_cylinders, car_range, version
+		FROM engine
+		WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE
+	`
+	err := tx.QueryRowContext(ctx, query, id).Scan(
+		&engine.EngineID,
+		&engine.Displacement,
+		&engine.NoOfCylinders,
+		&engine.CarRange,
+		&engine.Version,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return engine, models.NotFound("engine with ID %s does not exist", id)
+	} else if err != nil {
+		return engine, fmt.Errorf("failed to lock engine: %w", err)
+	}
+	return engine, nil
+}
+
+// auditCarChanges runs an UPDATE on the cars of a deleted engine and writes
+// an audit entry per car. The query returns each car's new row followed by
+// its previous engine_id and updated_at.
+func auditCarChanges(ctx context.Context, tx *sql.Tx, action models.AuditAction, query string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type change struct{ before, after models.Car }
+	var changes []change
+	for rows.Next() {
+		var c change
+		err := rows.Scan(
+			&c.after.ID,
+			&c.after.Name,
+			&c.after.Year,
+			&c.after.Brand,
+			&c.after.FuelType,
+			&c.after.Engine.EngineID,
+			&c.after.Price,
+			&c.after.CreatedAt,
+			&c.after.UpdatedAt,
+			&c.after.Version,
+			&c.before.Engine.EngineID,
+			&c.before.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		previousEngine, previousUpdatedAt := c.before.Engine, c.before.UpdatedAt
+		c.before = c.after
+		c.before.Engine, c.before.UpdatedAt = previousEngine, previousUpdatedAt
+		c.before.Version = c.after.Version - 1
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// The rows must be closed before the connection can run the inserts
+	rows.Close()
+
+	for _, c := range changes {
+		var after interface{} = c.after
+		if action == models.AuditDelete {
+			after = nil
+		}
+		if err := audit.Record(ctx, tx, action, models.AuditEntityCar, c.after.ID, c.before, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func carsUsingEngine(ctx context.Context, tx *sql.Tx, engineID string) ([]uuid.UUID, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM car WHERE engine_id = $1 AND deleted_at IS NULL ORDER BY created_at, id", engineID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dependent cars: %w", err)
+	}
+	defer rows.Close()
+
+	var carIDs []uuid.UUID
+	for rows.Next() {
+		var carID uuid.UUID
+		if err := rows.Scan(&carID); err != nil {
+			return nil, fmt.Errorf("failed to scan car ID: %w", err)
+		}
+		carIDs = append(carIDs, carID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+	return carIDs, nil
+}
+
+// engineSortColumns maps the sort fields accepted by models.ParseEngineSort
+// to columns. Only these names ever reach the ORDER BY clause.
+var engineSortColumns = map[string]string{
+	"displacement": "displacement",
+	"cylinders":    "no_of_cylinders",
+	"range":        "car_range",
+}
+
+func (e EngineStore) ListEngines(ctx context.Context, filter models.EngineFilter) (models.EnginePage, error) {
+	page := models.EnginePage{Engines: []models.Engine{}}
+
+	q := &store.Query{}
+	q.AddCondition("deleted_at IS NULL")
+	if filter.MinDisplacement != 0 {
+		q.Add("displacement >= $%d", filter.MinDisplacement)
+	}
+	if filter.MaxDisplacement != 0 {
+		q.Add("displacement <= $%d", filter.MaxDisplacement)
+	}
+	if filter.MinCylinders != 0 {
+		q.Add("no_of_cylinders >= $%d", filter.MinCylinders)
+	}
+	if filter.MaxCylinders != 0 {
+		q.Add("no_of_cylinders <= $%d", filter.MaxCylinders)
+	}
+	if filter.MinRange != 0 {
+		q.Add("car_range >= $%d", filter.MinRange)
+	}
+	if filter.MaxRange != 0 {
+		q.Add("car_range <= $%d", filter.MaxRange)
+	}
+
+	// Total count ignores the cursor so it stays the same across pages
+	err := e.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM engine"+q.Where(), q.Args...).Scan(&page.TotalCount)
+	if err != nil {
+		return page, fmt.Errorf("failed to count engines: %w", err)
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := models.DecodeCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return page, err
+		}
+		q.AddCursor(cursor, filter.Sort, engineSortColumns, "id")
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	limit := q.Placeholder(filter.Limit + 1)
+	query := `SELECT id, displacement, no_of_cylinders, car_range, version FROM engine` +
+		q.Where() + store.OrderBy(filter.Sort, engineSortColumns, "id") + fmt.Sprintf(" LIMIT $%d", limit)
+
+	rows, err := e.db.QueryContext(ctx, query, q.Args...)
+	if err != nil {
+		return page, fmt.Errorf("failed to fetch engines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var engine models.Engine
+		err := rows.Scan(
+			&engine.EngineID,
+			&engine.Displacement,
+			&engine.NoOfCylinders,
+			&engine.CarRange,
+			&engine.Version,
+		)
+		if err != nil {
+			return page, fmt.Errorf("failed to scan engine: %w", err)
+		}
+		page.Engines = append(page.Engines, engine)
+	}
+	if err = rows.Err(); err != nil {
+		return page, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	if len(page.Engines) > filter.Limit {
+		page.Engines = page.Engines[:filter.Limit]
+		page.NextCursor = models.EngineCursor(page.Engines[filter.Limit-1], filter.Sort)
+	}
+	return page, nil
+}
+
+// ImportEngines inserts already validated engines with COPY in one
+// transaction, auditing each, and returns them in the same order.
+func (e EngineStore) ImportEngines(ctx context.Context, engineReqs []models.EngineRequest) (createdEngines []models.Engine, err error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	createdEngines = make([]models.Engine, 0, len(engineReqs))
+	rows := make([][]interface{}, 0, len(engineReqs))
+	changes := make([]audit.Change, 0, len(engineReqs))
+	for _, engineReq := range engineReqs {
+		engine := models.Engine{
+			EngineID:      uuid.New(),
+			Displacement:  engineReq.Displacement,
+			NoOfCylinders: engineReq.NoOfCylinders,
+			CarRange:      engineReq.CarRange,
+			Version:       1,
+		}
+		createdEngines = append(createdEngines, engine)
+		rows = append(rows, []interface{}{engine.EngineID.String(), engine.Displacement, engine.NoOfCylinders, engine.CarRange})
+		changes = append(changes, audit.Change{EntityID: engine.EngineID, After: engine})
+	}
+
+	columns := []string{"id", "displacement", "no_of_cylinders", "car_range"}
+	if err = store.CopyIn(ctx, tx, "engine", columns, rows); err != nil {
+		return nil, err
+	}
+	if err = audit.RecordAll(ctx, tx, models.AuditCreate, models.AuditEntityEngine, changes); err != nil {
+		return nil, err
+	}
+
+	return createdEngines, nil
+}
@@ -0,0 +1,155 @@
+//go:build integration
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store/storetest"
+	"github.com/google/uuid"
+)
+
+func createEngine(t *testing.T, s *EngineStore, displacement, cylinders, carRange int64) models.Engine {
+	t.Helper()
+	engine, err := s.CreateEngine(context.Background(), &models.EngineRequest{
+		Displacement:  displacement,
+		NoOfCylinders: cylinders,
+		CarRange:      carRange,
+	})
+	if err != nil {
+		t.Fatalf("CreateEngine: %v", err)
+	}
+	return engine
+}
+
+func TestEngineCRUD(t *testing.T) {
+	s := New(storetest.NewDB(t))
+	ctx := context.Background()
+
+	created := createEngine(t, s, 2000, 4, 600)
+	if created.EngineID == uuid.Nil || created.Displacement != 2000 || created.NoOfCylinders != 4 || created.CarRange != 600 {
+		t.Fatalf("CreateEngine returned %+v", created)
+	}
+
+	got, err := s.EngineById(ctx, created.EngineID.String())
+	if err != nil {
+		t.Fatalf("EngineById: %v", err)
+	}
+	if got != created {
+		t.Fatalf("EngineById = %+v, want %+v", got, created)
+	}
+
+	updated, err := s.EngineUpdate(ctx, created.EngineID.String(), &models.EngineRequest{Displacement: 2000, NoOfCylinders: 6, CarRange: 450}, created.Version)
+	if err != nil {
+		t.Fatalf("EngineUpdate: %v", err)
+	}
+	want := models.Engine{EngineID: created.EngineID, Displacement: 2000, NoOfCylinders: 6, CarRange: 450, Version: 2}
+	if updated != want {
+		t.Fatalf("EngineUpdate = %+v, want %+v", updated, want)
+	}
+
+	_, err = s.EngineUpdate(ctx, created.EngineID.String(), &models.EngineRequest{Displacement: 2000, NoOfCylinders: 6, CarRange: 400}, created.Version)
+	if !errors.Is(err, models.ErrPreconditionFailed) {
+		t.Fatalf("EngineUpdate with stale version: got %v, want ErrPreconditionFailed", err)
+	}
+
+	deleted, err := s.EngineDelete(ctx, created.EngineID.String(), models.EngineDeleteOptions{})
+	if err != nil {
+		t.Fatalf("EngineDelete: %v", err)
+	}
+	want.Version++
+	if deleted != want {
+		t.Fatalf("EngineDelete = %+v, want %+v", deleted, want)
+	}
+
+	if _, err := s.EngineById(ctx, created.EngineID.String()); !errors.Is(err, models.ErrNotFound) {
+		t.Fatalf("EngineById after delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestEngineNotFound(t *testing.T) {
+	s := New(storetest.NewDB(t))
+	ctx := context.Background()
+	missing := uuid.NewString()
+
+	if _, err := s.EngineById(ctx, missing); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("EngineById: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.EngineUpdate(ctx, missing, &models.EngineRequest{Displacement: 1}, 0); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("EngineUpdate: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.EngineDelete(ctx, missing, models.EngineDeleteOptions{}); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("EngineDelete: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestListEngines(t *testing.T) {
+	s := New(storetest.NewDB(t))
+	ctx := context.Background()
+
+	for _, d := range []int64{1000, 1500, 2000, 2500, 3000} {
+		createEngine(t, s, d, 4, 500)
+	}
+
+	sort := []models.SortField{{Field: "displacement", Desc: true}}
+	filter := models.EngineFilter{MinDisplacement: 1500, Sort: sort, Limit: 3}
+	page, err := s.ListEngines(ctx, filter)
+	if err != nil {
+		t.Fatalf("ListEngines: %v", err)
+	}
+	if page.TotalCount != 4 || len(page.Engines) != 3 || page.NextCursor == "" {
+		t.Fatalf("first page: total %d, %d engines, cursor %q", page.TotalCount, len(page.Engines), page.NextCursor)
+	}
+
+	filter.Cursor = page.NextCursor
+	next, err := s.ListEngines(ctx, filter)
+	if err != nil {
+		t.Fatalf("ListEngines with cursor: %v", err)
+	}
+	if next.NextCursor != "" {
+		t.Errorf("last page has next cursor %q", next.NextCursor)
+	}
+
+	var got []int64
+	for _, e := range append(page.Engines, next.Engines...) {
+		got = append(got, e.Displacement)
+	}
+	want := []int64{3000, 2500, 2000, 1500}
+	if len(got) != len(want) {
+		t.Fatalf("displacements = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("displacements = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestImportEngines(t *testing.T) {
+	s := New(storetest.NewDB(t))
+	ctx := context.Background()
+
+	imported, err := s.ImportEngines(ctx, []models.EngineRequest{
+		{Displacement: 2000, NoOfCylinders: 4, CarRange: 600},
+		{Displacement: 3000, NoOfCylinders: 6, CarRange: 450},
+	})
+	if err != nil {
+		t.Fatalf("ImportEngines: %v", err)
+	}
+	if len(imported) != 2 {
+		t.Fatalf("ImportEngines returned %d engines, want 2", len(imported))
+	}
+
+	for _, want := range imported {
+		got, err := s.EngineById(ctx, want.EngineID.String())
+		if err != nil {
+			t.Fatalf("EngineById: %v", err)
+		}
+		if got != want {
+			t.Fatalf("EngineById = %+v, want %+v", got, want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// IsTimeout reports whether err comes from a query cut short by ctx's
+// deadline or by the session's statement_timeout. Postgres reports both as
+// query_canceled, and also a query canceled because the client went away,
+// so query_canceled only counts as a timeout while ctx is still live.
+func IsTimeout(ctx context.Context, err error) bool {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Name() == "query_canceled" && ctx.Err() == nil
+}
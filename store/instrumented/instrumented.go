@@ -0,0 +1,272 @@
+// Package instrumented wraps the store interfaces so every call is timed in
+// the store_call_duration_seconds histogram and traced as a span, whichever
+// backend is in use.
+package instrumented
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/MarNawar/carZone/metrics"
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store"
+	"github.com/MarNawar/carZone/tracing"
+	"github.com/google/uuid"
+)
+
+// observe starts the span for a store call, named like CarStore.GetCarById.
+// The returned function records the call's outcome and must be deferred with
+// the call's named error.
+func observe(ctx context.Context, storeName, method string) (context.Context, func(*error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, strings.ToUpper(storeName[:1])+storeName[1:]+"Store."+method)
+	return ctx, func(err *error) {
+		metrics.ObserveStore(storeName, method, start, err)
+		tracing.End(span, err)
+	}
+}
+
+type carStore struct {
+	next store.CarStoreInterface
+}
+
+func NewCarStore(next store.CarStoreInterface) store.CarStoreInterface {
+	return carStore{next: next}
+}
+
+func (s carStore) GetCarById(ctx context.Context, id string) (car models.Car, err error) {
+	ctx, done := observe(ctx, "car", "GetCarById")
+	defer done(&err)
+	return s.next.GetCarById(ctx, id)
+}
+
+func (s carStore) GetCarByBrand(ctx context.Context, brand string, isEngine bool) (cars []models.Car, err error) {
+	ctx, done := observe(ctx, "car", "GetCarByBrand")
+	defer done(&err)
+	return s.next.GetCarByBrand(ctx, brand, isEngine)
+}
+
+func (s carStore) ListCars(ctx context.Context, filter models.CarFilter) (page models.CarPage, err error) {
+	ctx, done := observe(ctx, "car", "ListCars")
+	defer done(&err)
+	return s.next.ListCars(ctx, filter)
+}
+
+func (s carStore) ExportCars(ctx context.Context, filter models.CarFilter, fn func(models.Car) error) (err error) {
+	ctx, done := observe(ctx, "car", "ExportCars")
+	defer done(&err)
+	return s.next.ExportCars(ctx, filter, fn)
+}
+
+func (s carStore) GetCarsByEngine(ctx context.Context, engineID string) (cars []models.Car, err error) {
+	ctx, done := observe(ctx, "car", "GetCarsByEngine")
+	defer done(&err)
+	return s.next.GetCarsByEngine(ctx, engineID)
+}
+
+func (s carStore) CreateCar(ctx context.Context, carReq *models.CarRequest) (car models.Car, err error) {
+	ctx, done := observe(ctx, "car", "CreateCar")
+	defer done(&err)
+	return s.next.CreateCar(ctx, carReq)
+}
+
+func (s carStore) GetEnginesByIDs(ctx context.Context, ids []uuid.UUID) (engines map[uuid.UUID]models.Engine, err error) {
+	ctx, done := observe(ctx, "car", "GetEnginesByIDs")
+	defer done(&err)
+	return s.next.GetEnginesByIDs(ctx, ids)
+}
+
+func (s carStore) ImportCars(ctx context.Context, carReqs []models.CarRequest) (cars []models.Car, err error) {
+	ctx, done := observe(ctx, "car", "ImportCars")
+	defer done(&err)
+	return s.next.ImportCars(ctx, carReqs)
+}
+
+func (s carStore) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, expectedVersion int64) (car models.Car, err error) {
+	ctx, done := observe(ctx, "car", "UpdateCar")
+	defer done(&err)
+	return s.next.UpdateCar(ctx, id, carReq, expectedVersion)
+}
+
+func (s carStore) DeleteCar(ctx context.Context, id string) (car models.Car, err error) {
+	ctx, done := observe(ctx, "car", "DeleteCar")
+	defer done(&err)
+	return s.next.DeleteCar(ctx, id)
+}
+
+func (s carStore) RestoreCar(ctx context.Context, id string) (car models.Car, err error) {
+	ctx, done := observe(ctx, "car", "RestoreCar")
+	defer done(&err)
+	return s.next.RestoreCar(ctx, id)
+}
+
+func (s carStore) PurgeCars(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	ctx, done := observe(ctx, "car", "PurgeCars")
+	defer done(&err)
+	return s.next.PurgeCars(ctx, deletedBefore)
+}
+
+func (s carStore) CountInventory(ctx context.Context) (counts []models.InventoryCount, err error) {
+	ctx, done := observe(ctx, "car", "CountInventory")
+	defer done(&err)
+	return s.next.CountInventory(ctx)
+}
+
+type engineStore struct {
+	next store.EngineStoreInterface
+}
+
+func NewEngineStore(next store.EngineStoreInterface) store.EngineStoreInterface {
+	return engineStore{next: next}
+}
+
+func (s engineStore) EngineById(ctx context.Context, id string) (engine models.Engine, err error) {
+	ctx, done := observe(ctx, "engine", "EngineById")
+	defer done(&err)
+	return s.next.EngineById(ctx, id)
+}
+
+func (s engineStore) ListEngines(ctx context.Context, filter models.EngineFilter) (page models.EnginePage, err error) {
+	ctx, done := observe(ctx, "engine", "ListEngines")
+	defer done(&err)
+	return s.next.ListEngines(ctx, filter)
+}
+
+func (s engineStore) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (engine models.Engine, err error) {
+	ctx, done := observe(ctx, "engine", "CreateEngine")
+	defer done(&err)
+	return s.next.CreateEngine(ctx, engineReq)
+}
+
+func (s engineStore) ImportEngines(ctx context.Context, engineReqs []models.EngineRequest) (engines []models.Engine, err error) {
+	ctx, done := observe(ctx, "engine", "ImportEngines")
+	defer done(&err)
+	return s.next.ImportEngines(ctx, engineReqs)
+}
+
+func (s engineStore) EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest, expectedVersion int64) (engine models.Engine, err error) {
+	ctx, done := observe(ctx, "engine", "EngineUpdate")
+	defer done(&err)
+	return s.next.EngineUpdate(ctx, id, engineReq, expectedVersion)
+}
+
+func (s engineStore) EngineDelete(ctx context.Context, id string, options models.EngineDeleteOptions) (engine models.Engine, err error) {
+	ctx, done := observe(ctx, "engine", "EngineDelete")
+	defer done(&err)
+	return s.next.EngineDelete(ctx, id, options)
+}
+
+func (s engineStore) RestoreEngine(ctx context.Context, id string) (engine models.Engine, err error) {
+	ctx, done := observe(ctx, "engine", "RestoreEngine")
+	defer done(&err)
+	return s.next.RestoreEngine(ctx, id)
+}
+
+func (s engineStore) PurgeEngines(ctx context.Context, deletedBefore time.Time) (purged int64, err error) {
+	ctx, done := observe(ctx, "engine", "PurgeEngines")
+	defer done(&err)
+	return s.next.PurgeEngines(ctx, deletedBefore)
+}
+
+type auditStore struct {
+	next store.AuditStoreInterface
+}
+
+func NewAuditStore(next store.AuditStoreInterface) store.AuditStoreInterface {
+	return auditStore{next: next}
+}
+
+func (s auditStore) ListAudit(ctx context.Context, filter models.AuditFilter) (entries []models.AuditEntry, err error) {
+	ctx, done := observe(ctx, "audit", "ListAudit")
+	defer done(&err)
+	return s.next.ListAudit(ctx, filter)
+}
+
+type userStore struct {
+	next store.UserStoreInterface
+}
+
+func NewUserStore(next store.UserStoreInterface) store.UserStoreInterface {
+	return userStore{next: next}
+}
+
+func (s userStore) GetUserByUsername(ctx context.Context, username string) (user models.User, err error) {
+	ctx, done := observe(ctx, "user", "GetUserByUsername")
+	defer done(&err)
+	return s.next.GetUserByUsername(ctx, username)
+}
+
+func (s userStore) CreateUser(ctx context.Context, username, passwordHash string, role models.Role) (user models.User, err error) {
+	ctx, done := observe(ctx, "user", "CreateUser")
+	defer done(&err)
+	return s.next.CreateUser(ctx, username, passwordHash, role)
+}
+
+func (s userStore) UpdatePassword(ctx context.Context, username, passwordHash string) (user models.User, err error) {
+	ctx, done := observe(ctx, "user", "UpdatePassword")
+	defer done(&err)
+	return s.next.UpdatePassword(ctx, username, passwordHash)
+}
+
+func (s userStore) UpdateRole(ctx context.Context, username string, role models.Role) (user models.User, err error) {
+	ctx, done := observe(ctx, "user", "UpdateRole")
+	defer done(&err)
+	return s.next.UpdateRole(ctx, username, role)
+}
+
+func (s userStore) UpdateStatus(ctx context.Context, username string, status *models.UserStatusRequest) (user models.User, err error) {
+	ctx, done := observe(ctx, "user", "UpdateStatus")
+	defer done(&err)
+	return s.next.UpdateStatus(ctx, username, status)
+}
+
+func (s userStore) RecordLoginFailure(ctx context.Context, username string, maxAttempts int) (user models.User, err error) {
+	ctx, done := observe(ctx, "user", "RecordLoginFailure")
+	defer done(&err)
+	return s.next.RecordLoginFailure(ctx, username, maxAttempts)
+}
+
+func (s userStore) ResetLoginFailures(ctx context.Context, username string) (err error) {
+	ctx, done := observe(ctx, "user", "ResetLoginFailures")
+	defer done(&err)
+	return s.next.ResetLoginFailures(ctx, username)
+}
+
+type tokenStore struct {
+	next store.TokenStoreInterface
+}
+
+func NewTokenStore(next store.TokenStoreInterface) store.TokenStoreInterface {
+	return tokenStore{next: next}
+}
+
+func (s tokenStore) CreateRefreshToken(ctx context.Context, token models.RefreshToken) (err error) {
+	ctx, done := observe(ctx, "token", "CreateRefreshToken")
+	defer done(&err)
+	return s.next.CreateRefreshToken(ctx, token)
+}
+
+func (s tokenStore) ConsumeRefreshToken(ctx context.Context, tokenHash string) (token models.RefreshToken, err error) {
+	ctx, done := observe(ctx, "token", "ConsumeRefreshToken")
+	defer done(&err)
+	return s.next.ConsumeRefreshToken(ctx, tokenHash)
+}
+
+func (s tokenStore) RevokeRefreshToken(ctx context.Context, tokenHash string) (err error) {
+	ctx, done := observe(ctx, "token", "RevokeRefreshToken")
+	defer done(&err)
+	return s.next.RevokeRefreshToken(ctx, tokenHash)
+}
+
+func (s tokenStore) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) (err error) {
+	ctx, done := observe(ctx, "token", "RevokeAccessToken")
+	defer done(&err)
+	return s.next.RevokeAccessToken(ctx, jti, expiresAt)
+}
+
+func (s tokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (revoked bool, err error) {
+	ctx, done := observe(ctx, "token", "IsAccessTokenRevoked")
+	defer done(&err)
+	return s.next.IsAccessTokenRevoked(ctx, jti)
+}
@@ -2,22 +2,57 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/MarNawar/carZone/models"
+	"github.com/google/uuid"
 )
 
 type CarStoreInterface interface {
 	GetCarById(context.Context, string) (models.Car, error)
 	GetCarByBrand(context.Context, string, bool) ([]models.Car, error)
+	ListCars(context.Context, models.CarFilter) (models.CarPage, error)
+	ExportCars(context.Context, models.CarFilter, func(models.Car) error) error
+	GetCarsByEngine(context.Context, string) ([]models.Car, error)
 	CreateCar(context.Context, *models.CarRequest) (models.Car, error)
-	UpdateCar(context.Context, string, *models.CarRequest) (models.Car, error)
+	GetEnginesByIDs(context.Context, []uuid.UUID) (map[uuid.UUID]models.Engine, error)
+	ImportCars(context.Context, []models.CarRequest) ([]models.Car, error)
+	UpdateCar(context.Context, string, *models.CarRequest, int64) (models.Car, error)
 	DeleteCar(context.Context, string) (models.Car, error)
+	RestoreCar(context.Context, string) (models.Car, error)
+	PurgeCars(context.Context, time.Time) (int64, error)
+	CountInventory(context.Context) ([]models.InventoryCount, error)
 }
 
 type EngineStoreInterface interface{
 	EngineById(context.Context, string) (models.Engine, error)
+	ListEngines(context.Context, models.EngineFilter) (models.EnginePage, error)
 	CreateEngine(context.Context, *models.EngineRequest) (models.Engine, error)
-	EngineUpdate(context.Context, string, *models.EngineRequest) (models.Engine, error) 
-	EngineDelete(context.Context, string) (models.Engine, error)
+	ImportEngines(context.Context, []models.EngineRequest) ([]models.Engine, error)
+	EngineUpdate(context.Context, string, *models.EngineRequest, int64) (models.Engine, error) 
+	EngineDelete(context.Context, string, models.EngineDeleteOptions) (models.Engine, error)
+	RestoreEngine(context.Context, string) (models.Engine, error)
+	PurgeEngines(context.Context, time.Time) (int64, error)
 }
 
+type AuditStoreInterface interface {
+	ListAudit(context.Context, models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+type UserStoreInterface interface {
+	GetUserByUsername(context.Context, string) (models.User, error)
+	CreateUser(context.Context, string, string, models.Role) (models.User, error)
+	UpdatePassword(context.Context, string, string) (models.User, error)
+	UpdateRole(context.Context, string, models.Role) (models.User, error)
+	UpdateStatus(context.Context, string, *models.UserStatusRequest) (models.User, error)
+	RecordLoginFailure(context.Context, string, int) (models.User, error)
+	ResetLoginFailures(context.Context, string) error
+}
+
+type TokenStoreInterface interface {
+	CreateRefreshToken(context.Context, models.RefreshToken) error
+	ConsumeRefreshToken(context.Context, string) (models.RefreshToken, error)
+	RevokeRefreshToken(context.Context, string) error
+	RevokeAccessToken(context.Context, string, time.Time) error
+	IsAccessTokenRevoked(context.Context, string) (bool, error)
+}
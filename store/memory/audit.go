@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/google/uuid"
+)
+
+// record appends an audit entry for a change made under the write lock, the
+// in-memory counterpart of writing audit_log in the same transaction.
+func (s *Store) record(ctx context.Context, action models.AuditAction, entity models.AuditEntity, entityID uuid.UUID, before, after interface{}) {
+	s.nextAuditID++
+	entry := models.AuditEntry{
+		ID:        s.nextAuditID,
+		Actor:     models.ActorFromContext(ctx),
+		Action:    action,
+		Entity:    entity,
+		EntityID:  entityID,
+		CreatedAt: time.Now(),
+	}
+	// Cars and engines always encode, so errors cannot happen here
+	if before != nil {
+		entry.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		entry.After, _ = json.Marshal(after)
+	}
+	s.auditLog = append(s.auditLog, entry)
+}
+
+func (s *Store) ListAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []models.AuditEntry{}
+	// The log is in ID order, so walk it backwards for newest first
+	for i := len(s.auditLog) - 1; i >= 0 && len(entries) < filter.Limit; i-- {
+		entry := s.auditLog[i]
+		switch {
+		case filter.Entity != "" && entry.Entity != filter.Entity,
+			filter.EntityID != "" && entry.EntityID.String() != filter.EntityID,
+			filter.Actor != "" && entry.Actor != filter.Actor,
+			filter.BeforeID != 0 && entry.ID >= filter.BeforeID:
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
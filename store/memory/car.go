@@ -0,0 +1,258 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/google/uuid"
+)
+
+func (s *Store) GetCarById(ctx context.Context, id string) (models.Car, error) {
+	var car models.Car
+	if err := ctx.Err(); err != nil {
+		return car, err
+	}
+
+	carID, err := models.ParseID(id)
+	if err != nil {
+		return car, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored, ok := s.cars[carID]
+	if !ok {
+		return car, models.NotFound("car with ID %s does not exist", id)
+	}
+	return s.withEngine(stored), nil
+}
+
+func (s *Store) GetCarByBrand(ctx context.Context, brand string, isEngine bool) ([]models.Car, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var cars []models.Car
+	for _, car := range s.cars {
+		if car.Brand != brand {
+			continue
+		}
+		if isEngine {
+			car = s.withEngine(car)
+		}
+		cars = append(cars, car)
+	}
+
+	// Map iteration order is random, keep the listing stable
+	sort.Slice(cars, func(i, j int) bool {
+		if cars[i].CreatedAt.Equal(cars[j].CreatedAt) {
+			return cars[i].ID.String() < cars[j].ID.String()
+		}
+		return cars[i].CreatedAt.Before(cars[j].CreatedAt)
+	})
+
+	return cars, nil
+}
+
+func (s *Store) CreateCar(ctx context.Context, carReq *models.CarRequest) (models.Car, error) {
+	var createdCar models.Car
+	if err := ctx.Err(); err != nil {
+		return createdCar, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Validate engine existence
+	if _, ok := s.engines[carReq.Engine.EngineID]; !ok {
+		return createdCar, models.Validation("engine with ID %s does not exist", carReq.Engine.EngineID)
+	}
+
+	currentTime := time.Now()
+	createdCar = models.Car{
+		ID:        uuid.New(),
+		Name:      carReq.Name,
+		Year:      carReq.Year,
+		Brand:     carReq.Brand,
+		FuelType:  carReq.FuelType,
+		Engine:    models.Engine{EngineID: carReq.Engine.EngineID},
+		Price:     carReq.Price,
+		CreatedAt: currentTime,
+		UpdatedAt: currentTime,
+		Version:   1,
+	}
+	s.cars[createdCar.ID] = createdCar
+	s.record(ctx, models.AuditCreate, models.AuditEntityCar, createdCar.ID, nil, createdCar)
+
+	return createdCar, nil
+}
+
+func (s *Store) UpdateCar(ctx context.Context, id string, carReq *models.CarRequest, expectedVersion int64) (models.Car, error) {
+	var updatedCar models.Car
+	if err := ctx.Err(); err != nil {
+		return updatedCar, err
+	}
+
+	carID, err := models.ParseID(id)
+	if err != nil {
+		return updatedCar, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, ok := s.cars[carID]
+	if !ok {
+		return models.Car{}, models.NotFound("car with ID %s does not exist", id)
+	}
+	if expectedVersion != 0 && before.Version != expectedVersion {
+		return models.Car{}, models.StaleVersion("car", id, expectedVersion, before.Version)
+	}
+	updatedCar = before
+
+	// Every field is replaced, same as the SQL store
+	if _, ok := s.engines[carReq.Engine.EngineID]; !ok {
+		return models.Car{}, models.Validation("engine with ID %s does not exist", carReq.Engine.EngineID)
+	}
+	updatedCar.Name = carReq.Name
+	updatedCar.Year = carReq.Year
+	updatedCar.Brand = carReq.Brand
+	updatedCar.FuelType = carReq.FuelType
+	updatedCar.Engine = models.Engine{EngineID: carReq.Engine.EngineID}
+	updatedCar.Price = carReq.Price
+	updatedCar.UpdatedAt = time.Now()
+	updatedCar.Version++
+
+	s.cars[carID] = updatedCar
+	s.record(ctx, models.AuditUpdate, models.AuditEntityCar, carID, before, updatedCar)
+
+	return updatedCar, nil
+}
+
+func (s *Store) DeleteCar(ctx context.Context, id string) (models.Car, error) {
+	var deletedCar models.Car
+	if err := ctx.Err(); err != nil {
+		return deletedCar, err
+	}
+
+	carID, err := models.ParseID(id)
+	if err != nil {
+		return deletedCar, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deletedCar, ok := s.cars[carID]
+	if !ok {
+		return models.Car{}, models.NotFound("car with ID %s does not exist", id)
+	}
+	deletedCar.Version++
+	s.softDeleteCar(deletedCar, time.Now())
+	s.record(ctx, models.AuditDelete, models.AuditEntityCar, carID, deletedCar, nil)
+
+	return deletedCar, nil
+}
+
+func (s *Store) RestoreCar(ctx context.Context, id string) (models.Car, error) {
+	var restoredCar models.Car
+	if err := ctx.Err(); err != nil {
+		return restoredCar, err
+	}
+
+	carID, err := models.ParseID(id)
+	if err != nil {
+		return restoredCar, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.cars[carID]; ok {
+		return restoredCar, models.Conflict("car with ID %s is not deleted", id)
+	}
+	restoredCar, ok := s.deletedCars[carID]
+	if !ok {
+		return models.Car{}, models.NotFound("car with ID %s does not exist", id)
+	}
+	if _, ok := s.engines[restoredCar.Engine.EngineID]; !ok {
+		return models.Car{}, models.Conflict("the engine of car %s is deleted; restore the engine first", id)
+	}
+
+	restoredCar.UpdatedAt = time.Now()
+	restoredCar.Version++
+	delete(s.deletedCars, carID)
+	delete(s.deletedAt, carID)
+	s.cars[carID] = restoredCar
+	s.record(ctx, models.AuditRestore, models.AuditEntityCar, carID, nil, restoredCar)
+
+	return restoredCar, nil
+}
+
+func (s *Store) PurgeCars(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for carID := range s.deletedCars {
+		if s.deletedAt[carID].Before(deletedBefore) {
+			s.record(ctx, models.AuditPurge, models.AuditEntityCar, carID, s.deletedCars[carID], nil)
+			delete(s.deletedCars, carID)
+			delete(s.deletedAt, carID)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// softDeleteCar moves a car out of the live set. Callers must hold the
+// write lock.
+func (s *Store) softDeleteCar(car models.Car, at time.Time) {
+	delete(s.cars, car.ID)
+	s.deletedCars[car.ID] = car
+	s.deletedAt[car.ID] = at
+}
+
+func (s *Store) GetCarsByEngine(ctx context.Context, engineID string) ([]models.Car, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	id, err := models.ParseID(engineID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.engines[id]; !ok {
+		return nil, models.NotFound("engine with ID %s does not exist", engineID)
+	}
+
+	cars := []models.Car{}
+	for _, car := range s.cars {
+		if car.Engine.EngineID == id {
+			cars = append(cars, s.withEngine(car))
+		}
+	}
+
+	sort.Slice(cars, func(i, j int) bool {
+		if cars[i].CreatedAt.Equal(cars[j].CreatedAt) {
+			return cars[i].ID.String() < cars[j].ID.String()
+		}
+		return cars[i].CreatedAt.Before(cars[j].CreatedAt)
+	})
+
+	return cars, nil
+}
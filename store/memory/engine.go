@@ -0,0 +1,224 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/google/uuid"
+)
+
+func (s *Store) EngineById(ctx context.Context, id string) (models.Engine, error) {
+	var engine models.Engine
+	if err := ctx.Err(); err != nil {
+		return engine, err
+	}
+
+	engineID, err := models.ParseID(id)
+	if err != nil {
+		return engine, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	engine, ok := s.engines[engineID]
+	if !ok {
+		return models.Engine{}, models.NotFound("engine with ID %s does not exist", id)
+	}
+	return engine, nil
+}
+
+func (s *Store) CreateEngine(ctx context.Context, engineReq *models.EngineRequest) (models.Engine, error) {
+	var createdEngine models.Engine
+	if err := ctx.Err(); err != nil {
+		return createdEngine, err
+	}
+
+	createdEngine = models.Engine{
+		EngineID:      uuid.New(),
+		Displacement:  engineReq.Displacement,
+		NoOfCylinders: engineReq.NoOfCylinders,
+		CarRange:      engineReq.CarRange,
+		Version:       1,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.engines[createdEngine.EngineID] = createdEngine
+	s.record(ctx, models.AuditCreate, models.AuditEntityEngine, createdEngine.EngineID, nil, createdEngine)
+
+	return createdEngine, nil
+}
+
+func (s *Store) EngineUpdate(ctx context.Context, id string, engineReq *models.EngineRequest, expectedVersion int64) (models.Engine, error) {
+	var updatedEngine models.Engine
+	if err := ctx.Err(); err != nil {
+		return updatedEngine, err
+	}
+
+	engineID, err := models.ParseID(id)
+	if err != nil {
+		return updatedEngine, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, ok := s.engines[engineID]
+	if !ok {
+		return models.Engine{}, models.NotFound("engine with ID %s does not exist", id)
+	}
+	if expectedVersion != 0 && before.Version != expectedVersion {
+		return models.Engine{}, models.StaleVersion("engine", id, expectedVersion, before.Version)
+	}
+	updatedEngine = before
+
+	updatedEngine.Displacement = engineReq.Displacement
+	updatedEngine.NoOfCylinders = engineReq.NoOfCylinders
+	updatedEngine.CarRange = engineReq.CarRange
+
+	updatedEngine.Version++
+	s.engines[engineID] = updatedEngine
+	s.record(ctx, models.AuditUpdate, models.AuditEntityEngine, engineID, before, updatedEngine)
+
+	return updatedEngine, nil
+}
+
+func (s *Store) EngineDelete(ctx context.Context, id string, options models.EngineDeleteOptions) (models.Engine, error) {
+	var deletedEngine models.Engine
+	if err := ctx.Err(); err != nil {
+		return deletedEngine, err
+	}
+
+	engineID, err := models.ParseID(id)
+	if err != nil {
+		return deletedEngine, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deletedEngine, ok := s.engines[engineID]
+	if !ok {
+		return models.Engine{}, models.NotFound("engine with ID %s does not exist", id)
+	}
+
+	now := time.Now()
+	var dependents []models.Car
+	for _, car := range s.cars {
+		if car.Engine.EngineID == engineID {
+			dependents = append(dependents, car)
+		}
+	}
+
+	switch {
+	case options.ReassignTo != "":
+		targetID, err := models.ParseID(options.ReassignTo)
+		if err != nil {
+			return models.Engine{}, err
+		}
+		if _, ok := s.engines[targetID]; !ok {
+			return models.Engine{}, models.Validation("engine with ID %s does not exist", options.ReassignTo)
+		}
+		for _, car := range dependents {
+			before := car
+			car.Engine = models.Engine{EngineID: targetID}
+			car.UpdatedAt = now
+			car.Version++
+			s.cars[car.ID] = car
+			s.record(ctx, models.AuditUpdate, models.AuditEntityCar, car.ID, before, car)
+		}
+	case options.Cascade:
+		for _, car := range dependents {
+			// Like the SQL store, the audit entry keeps the car as it was
+			// before the deletion bumped its version
+			before := car
+			car.Version++
+			s.softDeleteCar(car, now)
+			s.record(ctx, models.AuditDelete, models.AuditEntityCar, car.ID, before, nil)
+		}
+	case len(dependents) > 0:
+		// Match the SQL store's ordering of the dependent IDs
+		sort.Slice(dependents, func(i, j int) bool {
+			if dependents[i].CreatedAt.Equal(dependents[j].CreatedAt) {
+				return dependents[i].ID.String() < dependents[j].ID.String()
+			}
+			return dependents[i].CreatedAt.Before(dependents[j].CreatedAt)
+		})
+		carIDs := make([]uuid.UUID, len(dependents))
+		for i, car := range dependents {
+			carIDs[i] = car.ID
+		}
+		return models.Engine{}, models.EngineInUse(id, carIDs)
+	}
+
+	deletedEngine.Version++
+	delete(s.engines, engineID)
+	s.deletedEngines[engineID] = deletedEngine
+	s.deletedAt[engineID] = now
+	s.record(ctx, models.AuditDelete, models.AuditEntityEngine, engineID, deletedEngine, nil)
+	return deletedEngine, nil
+}
+
+func (s *Store) RestoreEngine(ctx context.Context, id string) (models.Engine, error) {
+	var restoredEngine models.Engine
+	if err := ctx.Err(); err != nil {
+		return restoredEngine, err
+	}
+
+	engineID, err := models.ParseID(id)
+	if err != nil {
+		return restoredEngine, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.engines[engineID]; ok {
+		return restoredEngine, models.Conflict("engine with ID %s is not deleted", id)
+	}
+	restoredEngine, ok := s.deletedEngines[engineID]
+	if !ok {
+		return models.Engine{}, models.NotFound("engine with ID %s does not exist", id)
+	}
+
+	restoredEngine.Version++
+	delete(s.deletedEngines, engineID)
+	delete(s.deletedAt, engineID)
+	s.engines[engineID] = restoredEngine
+	s.record(ctx, models.AuditRestore, models.AuditEntityEngine, engineID, nil, restoredEngine)
+
+	return restoredEngine, nil
+}
+
+// PurgeEngines skips engines a car still points to, like the RESTRICT
+// foreign key does in Postgres.
+func (s *Store) PurgeEngines(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	referenced := make(map[uuid.UUID]bool)
+	for _, cars := range []map[uuid.UUID]models.Car{s.cars, s.deletedCars} {
+		for _, car := range cars {
+			referenced[car.Engine.EngineID] = true
+		}
+	}
+
+	var purged int64
+	for engineID := range s.deletedEngines {
+		if s.deletedAt[engineID].Before(deletedBefore) && !referenced[engineID] {
+			s.record(ctx, models.AuditPurge, models.AuditEntityEngine, engineID, s.deletedEngines[engineID], nil)
+			delete(s.deletedEngines, engineID)
+			delete(s.deletedAt, engineID)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/google/uuid"
+)
+
+func (s *Store) GetEnginesByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.Engine, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	engines := make(map[uuid.UUID]models.Engine, len(ids))
+	for _, id := range ids {
+		if engine, ok := s.engines[id]; ok {
+			engines[id] = engine
+		}
+	}
+	return engines, nil
+}
+
+// ImportCars inserts all of carReqs or, if any engine is missing, none of
+// them, like the single transaction of the SQL store.
+func (s *Store) ImportCars(ctx context.Context, carReqs []models.CarRequest) ([]models.Car, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, carReq := range carReqs {
+		if _, ok := s.engines[carReq.Engine.EngineID]; !ok {
+			return nil, models.Validation("engine with ID %s does not exist", carReq.Engine.EngineID)
+		}
+	}
+
+	currentTime := time.Now()
+	createdCars := make([]models.Car, 0, len(carReqs))
+	for _, carReq := range carReqs {
+		car := models.Car{
+			ID:        uuid.New(),
+			Name:      carReq.Name,
+			Year:      carReq.Year,
+			Brand:     carReq.Brand,
+			FuelType:  carReq.FuelType,
+			Engine:    models.Engine{EngineID: carReq.Engine.EngineID},
+			Price:     carReq.Price,
+			CreatedAt: currentTime,
+			UpdatedAt: currentTime,
+			Version:   1,
+		}
+		s.cars[car.ID] = car
+		s.record(ctx, models.AuditCreate, models.AuditEntityCar, car.ID, nil, car)
+		createdCars = append(createdCars, car)
+	}
+
+	return createdCars, nil
+}
+
+func (s *Store) ImportEngines(ctx context.Context, engineReqs []models.EngineRequest) ([]models.Engine, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	createdEngines := make([]models.Engine, 0, len(engineReqs))
+	for _, engineReq := range engineReqs {
+		engine := models.Engine{
+			EngineID:      uuid.New(),
+			Displacement:  engineReq.Displacement,
+			NoOfCylinders: engineReq.NoOfCylinders,
+			CarRange:      engineReq.CarRange,
+			Version:       1,
+		}
+		s.engines[engine.EngineID] = engine
+		s.record(ctx, models.AuditCreate, models.AuditEntityEngine, engine.EngineID, nil, engine)
+		createdEngines = append(createdEngines, engine)
+	}
+
+	return createdEngines, nil
+}
@@ -0,0 +1,273 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+)
+
+func (s *Store) ListCars(ctx context.Context, filter models.CarFilter) (models.CarPage, error) {
+	page := models.CarPage{Cars: []models.Car{}}
+	if err := ctx.Err(); err != nil {
+		return page, err
+	}
+
+	var cursor *models.Cursor
+	if filter.Cursor != "" {
+		decoded, err := models.DecodeCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return page, err
+		}
+		cursor = &decoded
+	}
+
+	s.mu.RLock()
+	var matched []models.Car
+	for _, car := range s.cars {
+		car = s.withEngine(car)
+		if matchesCarFilter(car, filter) {
+			matched = append(matched, car)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return compareCars(matched[i], matched[j], filter.Sort) < 0
+	})
+	page.TotalCount = int64(len(matched))
+
+	for _, car := range matched {
+		if cursor != nil && compareCarToCursor(car, *cursor, filter.Sort) <= 0 {
+			continue
+		}
+		if len(page.Cars) == filter.Limit {
+			page.NextCursor = models.CarCursor(page.Cars[len(page.Cars)-1], filter.Sort)
+			break
+		}
+		page.Cars = append(page.Cars, car)
+	}
+	return page, nil
+}
+
+// ExportCars calls fn for every matching car in order. The matches are
+// copied first so fn runs without the lock held.
+func (s *Store) ExportCars(ctx context.Context, filter models.CarFilter, fn func(models.Car) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	var matched []models.Car
+	for _, car := range s.cars {
+		car = s.withEngine(car)
+		if matchesCarFilter(car, filter) {
+			matched = append(matched, car)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return compareCars(matched[i], matched[j], filter.Sort) < 0
+	})
+	for _, car := range matched {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(car); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchesCarFilter(car models.Car, filter models.CarFilter) bool {
+	year, _ := strconv.Atoi(car.Year)
+
+	switch {
+	case filter.Brand != "" && car.Brand != filter.Brand,
+		filter.Name != "" && !strings.Contains(strings.ToLower(car.Name), strings.ToLower(filter.Name)),
+		filter.FuelType != "" && car.FuelType != filter.FuelType,
+		filter.MinYear != 0 && year < filter.MinYear,
+		filter.MaxYear != 0 && year > filter.MaxYear,
+		filter.MinPrice != 0 && car.Price < filter.MinPrice,
+		filter.MaxPrice != 0 && car.Price > filter.MaxPrice,
+		filter.MinDisplacement != 0 && car.Engine.Displacement < filter.MinDisplacement,
+		filter.MaxDisplacement != 0 && car.Engine.Displacement > filter.MaxDisplacement,
+		filter.MinCylinders != 0 && car.Engine.NoOfCylinders < filter.MinCylinders,
+		filter.MaxCylinders != 0 && car.Engine.NoOfCylinders > filter.MaxCylinders,
+		filter.MinRange != 0 && car.Engine.CarRange < filter.MinRange,
+		filter.MaxRange != 0 && car.Engine.CarRange > filter.MaxRange:
+		return false
+	}
+	return true
+}
+
+func compareCars(a, b models.Car, sortFields []models.SortField) int {
+	for _, f := range sortFields {
+		if c := compareCarField(a, f.Field, models.CarSortValue(b, f.Field)); c != 0 {
+			if f.Desc {
+				return -c
+			}
+			return c
+		}
+	}
+	return strings.Compare(a.ID.String(), b.ID.String())
+}
+
+func compareCarToCursor(car models.Car, cursor models.Cursor, sortFields []models.SortField) int {
+	for i, f := range sortFields {
+		if c := compareCarField(car, f.Field, cursor.Values[i]); c != 0 {
+			if f.Desc {
+				return -c
+			}
+			return c
+		}
+	}
+	return strings.Compare(car.ID.String(), cursor.ID)
+}
+
+// compareCarField compares a car's field against a cursor-encoded value,
+// numerically or chronologically where the column type calls for it.
+func compareCarField(car models.Car, field string, value string) int {
+	switch field {
+	case "price":
+		other, _ := strconv.ParseFloat(value, 64)
+		switch {
+		case car.Price < other:
+			return -1
+		case car.Price > other:
+			return 1
+		}
+		return 0
+	case "created_at", "updated_at":
+		other, _ := time.Parse(time.RFC3339Nano, value)
+		t := car.CreatedAt
+		if field == "updated_at" {
+			t = car.UpdatedAt
+		}
+		return t.Compare(other)
+	}
+	return strings.Compare(models.CarSortValue(car, field), value)
+}
+
+func (s *Store) ListEngines(ctx context.Context, filter models.EngineFilter) (models.EnginePage, error) {
+	page := models.EnginePage{Engines: []models.Engine{}}
+	if err := ctx.Err(); err != nil {
+		return page, err
+	}
+
+	var cursor *models.Cursor
+	if filter.Cursor != "" {
+		decoded, err := models.DecodeCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return page, err
+		}
+		cursor = &decoded
+	}
+
+	s.mu.RLock()
+	var matched []models.Engine
+	for _, engine := range s.engines {
+		if matchesEngineFilter(engine, filter) {
+			matched = append(matched, engine)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return compareEngines(matched[i], matched[j], filter.Sort) < 0
+	})
+	page.TotalCount = int64(len(matched))
+
+	for _, engine := range matched {
+		if cursor != nil && compareEngineToCursor(engine, *cursor, filter.Sort) <= 0 {
+			continue
+		}
+		if len(page.Engines) == filter.Limit {
+			page.NextCursor = models.EngineCursor(page.Engines[len(page.Engines)-1], filter.Sort)
+			break
+		}
+		page.Engines = append(page.Engines, engine)
+	}
+	return page, nil
+}
+
+func matchesEngineFilter(engine models.Engine, filter models.EngineFilter) bool {
+	switch {
+	case filter.MinDisplacement != 0 && engine.Displacement < filter.MinDisplacement,
+		filter.MaxDisplacement != 0 && engine.Displacement > filter.MaxDisplacement,
+		filter.MinCylinders != 0 && engine.NoOfCylinders < filter.MinCylinders,
+		filter.MaxCylinders != 0 && engine.NoOfCylinders > filter.MaxCylinders,
+		filter.MinRange != 0 && engine.CarRange < filter.MinRange,
+		filter.MaxRange != 0 && engine.CarRange > filter.MaxRange:
+		return false
+	}
+	return true
+}
+
+func compareEngines(a, b models.Engine, sortFields []models.SortField) int {
+	for _, f := range sortFields {
+		if c := compareEngineField(a, f.Field, models.EngineSortValue(b, f.Field)); c != 0 {
+			if f.Desc {
+				return -c
+			}
+			return c
+		}
+	}
+	return strings.Compare(a.EngineID.String(), b.EngineID.String())
+}
+
+func compareEngineToCursor(engine models.Engine, cursor models.Cursor, sortFields []models.SortField) int {
+	for i, f := range sortFields {
+		if c := compareEngineField(engine, f.Field, cursor.Values[i]); c != 0 {
+			if f.Desc {
+				return -c
+			}
+			return c
+		}
+	}
+	return strings.Compare(engine.EngineID.String(), cursor.ID)
+}
+
+// compareEngineField compares an engine's numeric field against a
+// cursor-encoded value.
+func compareEngineField(engine models.Engine, field string, value string) int {
+	current, _ := strconv.ParseInt(models.EngineSortValue(engine, field), 10, 64)
+	other, _ := strconv.ParseInt(value, 10, 64)
+	switch {
+	case current < other:
+		return -1
+	case current > other:
+		return 1
+	}
+	return 0
+}
+
+func (s *Store) CountInventory(ctx context.Context) ([]models.InventoryCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	byKey := make(map[[2]string]int64)
+	for _, car := range s.cars {
+		byKey[[2]string{car.Brand, car.FuelType}]++
+	}
+	s.mu.RUnlock()
+
+	counts := []models.InventoryCount{}
+	for key, cars := range byKey {
+		counts = append(counts, models.InventoryCount{Brand: key[0], FuelType: key[1], Cars: cars})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Brand != counts[j].Brand {
+			return counts[i].Brand < counts[j].Brand
+		}
+		return counts[i].FuelType < counts[j].FuelType
+	})
+	return counts, nil
+}
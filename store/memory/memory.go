@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/MarNawar/carZone/store"
+	"github.com/google/uuid"
+)
+
+var (
+	_ store.CarStoreInterface    = (*Store)(nil)
+	_ store.EngineStoreInterface = (*Store)(nil)
+	_ store.UserStoreInterface   = (*Store)(nil)
+	_ store.TokenStoreInterface  = (*Store)(nil)
+	_ store.AuditStoreInterface  = (*Store)(nil)
+)
+
+// Store is a thread-safe, in-memory implementation of the store interfaces,
+// keyed the same way as the Postgres tables. Cars and engines
+// share one lock so the engine-existence check and the engine deletion
+// policy behave like the foreign key in the Postgres schema.
+type Store struct {
+	mu      sync.RWMutex
+	cars    map[uuid.UUID]models.Car
+	engines map[uuid.UUID]models.Engine
+	users   map[string]models.User
+
+	// Soft-deleted rows are moved out of cars and engines, so every lookup
+	// skips them without checking, and kept here until purged
+	deletedCars    map[uuid.UUID]models.Car
+	deletedEngines map[uuid.UUID]models.Engine
+	deletedAt      map[uuid.UUID]time.Time
+
+	auditLog    []models.AuditEntry
+	nextAuditID int64
+
+	refreshTokens map[string]models.RefreshToken
+	revokedTokens map[string]time.Time
+}
+
+func New() *Store {
+	return &Store{
+		cars:    make(map[uuid.UUID]models.Car),
+		engines: make(map[uuid.UUID]models.Engine),
+		users:   make(map[string]models.User),
+
+		deletedCars:    make(map[uuid.UUID]models.Car),
+		deletedEngines: make(map[uuid.UUID]models.Engine),
+		deletedAt:      make(map[uuid.UUID]time.Time),
+
+		refreshTokens: make(map[string]models.RefreshToken),
+		revokedTokens: make(map[string]time.Time),
+	}
+}
+
+// withEngine fills in the engine details of a stored car, mirroring the
+// JOIN done by the SQL store. Callers must hold at least a read lock.
+func (s *Store) withEngine(car models.Car) models.Car {
+	if engine, ok := s.engines[car.Engine.EngineID]; ok {
+		car.Engine = engine
+	}
+	return car
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+)
+
+func (s *Store) CreateRefreshToken(ctx context.Context, refreshToken models.RefreshToken) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshTokens[refreshToken.TokenHash] = refreshToken
+	return nil
+}
+
+func (s *Store) ConsumeRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refreshToken, ok := s.refreshTokens[tokenHash]
+	if !ok || !refreshToken.ExpiresAt.After(time.Now()) {
+		return models.RefreshToken{}, models.ErrInvalidToken
+	}
+	delete(s.refreshTokens, tokenHash)
+
+	return refreshToken, nil
+}
+
+func (s *Store) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.refreshTokens, tokenHash)
+	return nil
+}
+
+func (s *Store) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokedTokens[jti] = expiresAt
+
+	// Entries are only needed until the token would have expired anyway
+	now := time.Now()
+	for revokedJTI, revokedUntil := range s.revokedTokens {
+		if revokedUntil.Before(now) {
+			delete(s.revokedTokens, revokedJTI)
+		}
+	}
+	return nil
+}
+
+func (s *Store) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, revoked := s.revokedTokens[jti]
+	return revoked, nil
+}
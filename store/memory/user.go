@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/google/uuid"
+)
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[username]
+	if !ok {
+		return models.User{}, fmt.Errorf("%w: %s", models.ErrUserNotFound, username)
+	}
+	return user, nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, username string, passwordHash string, role models.Role) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[username]; ok {
+		return models.User{}, fmt.Errorf("%w: %s", models.ErrUserExists, username)
+	}
+
+	currentTime := time.Now()
+	user := models.User{
+		ID:           uuid.New(),
+		UserName:     username,
+		PasswordHash: passwordHash,
+		Role:         role,
+		CreatedAt:    currentTime,
+		UpdatedAt:    currentTime,
+	}
+	s.users[username] = user
+
+	return user, nil
+}
+
+func (s *Store) UpdatePassword(ctx context.Context, username string, passwordHash string) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[username]
+	if !ok {
+		return models.User{}, fmt.Errorf("%w: %s", models.ErrUserNotFound, username)
+	}
+	user.PasswordHash = passwordHash
+	user.UpdatedAt = time.Now()
+	s.users[username] = user
+
+	return user, nil
+}
+
+func (s *Store) UpdateRole(ctx context.Context, username string, role models.Role) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[username]
+	if !ok {
+		return models.User{}, fmt.Errorf("%w: %s", models.ErrUserNotFound, username)
+	}
+	user.Role = role
+	user.UpdatedAt = time.Now()
+	s.users[username] = user
+
+	return user, nil
+}
+
+func (s *Store) UpdateStatus(ctx context.Context, username string, statusReq *models.UserStatusRequest) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[username]
+	if !ok {
+		return models.User{}, fmt.Errorf("%w: %s", models.ErrUserNotFound, username)
+	}
+	user.Disabled = statusReq.Disabled
+	user.Locked = statusReq.Locked
+	if !statusReq.Locked {
+		user.FailedLoginAttempts = 0
+	}
+	user.UpdatedAt = time.Now()
+	s.users[username] = user
+
+	return user, nil
+}
+
+func (s *Store) RecordLoginFailure(ctx context.Context, username string, maxAttempts int) (models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return models.User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[username]
+	if !ok {
+		return models.User{}, fmt.Errorf("%w: %s", models.ErrUserNotFound, username)
+	}
+	user.FailedLoginAttempts++
+	if user.FailedLoginAttempts >= maxAttempts {
+		user.Locked = true
+	}
+	user.UpdatedAt = time.Now()
+	s.users[username] = user
+
+	return user, nil
+}
+
+func (s *Store) ResetLoginFailures(ctx context.Context, username string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[username]; ok {
+		user.FailedLoginAttempts = 0
+		s.users[username] = user
+	}
+	return nil
+}
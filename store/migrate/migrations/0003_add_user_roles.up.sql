@@ -0,0 +1,9 @@
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'viewer';
+
+ALTER TABLE users
+DROP CONSTRAINT IF EXISTS chk_users_role;
+
+ALTER TABLE users
+ADD CONSTRAINT chk_users_role
+CHECK (role IN ('viewer', 'editor', 'admin'));
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_car_engine_id;
+
+ALTER TABLE car
+DROP CONSTRAINT IF EXISTS fk_engine_id;
+
+ALTER TABLE car
+ADD CONSTRAINT fk_engine_id
+FOREIGN KEY (engine_id)
+REFERENCES engine(id)
+ON DELETE CASCADE;
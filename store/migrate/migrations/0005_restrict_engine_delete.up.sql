@@ -0,0 +1,12 @@
+-- Deleting an engine must not silently delete its cars; the store decides
+-- what happens to them and the constraint catches anything it missed
+ALTER TABLE car
+DROP CONSTRAINT IF EXISTS fk_engine_id;
+
+ALTER TABLE car
+ADD CONSTRAINT fk_engine_id
+FOREIGN KEY (engine_id)
+REFERENCES engine(id)
+ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS idx_car_engine_id ON car (engine_id);
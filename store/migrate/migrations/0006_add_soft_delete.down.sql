@@ -0,0 +1,14 @@
+-- Soft-deleted rows would reappear once the column is gone
+DELETE FROM car WHERE deleted_at IS NOT NULL;
+DELETE FROM engine e
+WHERE e.deleted_at IS NOT NULL
+AND NOT EXISTS (SELECT 1 FROM car c WHERE c.engine_id = e.id);
+
+DROP INDEX IF EXISTS idx_car_deleted_at;
+DROP INDEX IF EXISTS idx_engine_deleted_at;
+
+ALTER TABLE car
+DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE engine
+DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,9 @@
+-- Deleted cars and engines keep their row until the purge job removes it
+ALTER TABLE engine
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL;
+
+ALTER TABLE car
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL;
+
+CREATE INDEX IF NOT EXISTS idx_engine_deleted_at ON engine (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_car_deleted_at ON car (deleted_at) WHERE deleted_at IS NOT NULL;
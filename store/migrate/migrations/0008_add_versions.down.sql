@@ -0,0 +1,5 @@
+ALTER TABLE car
+DROP COLUMN IF EXISTS version;
+
+ALTER TABLE engine
+DROP COLUMN IF EXISTS version;
@@ -0,0 +1,6 @@
+-- Bumped on every change; clients send it back in If-Match
+ALTER TABLE engine
+ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE car
+ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
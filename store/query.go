@@ -0,0 +1,91 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MarNawar/carZone/models"
+)
+
+// Query collects WHERE conditions with numbered placeholders for the list
+// endpoints. Column names always come from the caller's whitelist, never
+// from the request.
+type Query struct {
+	conditions []string
+	Args       []interface{}
+}
+
+// Add appends a condition; its single %d is replaced by arg's placeholder.
+func (q *Query) Add(condition string, arg interface{}) {
+	q.conditions = append(q.conditions, fmt.Sprintf(condition, q.Placeholder(arg)))
+}
+
+// AddCondition appends a condition that takes no arguments.
+func (q *Query) AddCondition(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// Placeholder binds arg and returns its $n number.
+func (q *Query) Placeholder(arg interface{}) int {
+	q.Args = append(q.Args, arg)
+	return len(q.Args)
+}
+
+func (q *Query) Where() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
+
+// AddCursor restricts the query to rows strictly after the cursor in sort
+// order, expanding (a, b, id) > (x, y, z) into an OR chain so each field can
+// have its own direction.
+func (q *Query) AddCursor(cursor models.Cursor, sort []models.SortField, columns map[string]string, idColumn string) {
+	sortColumns := make([]string, 0, len(sort)+1)
+	operators := make([]string, 0, len(sort)+1)
+	placeholders := make([]string, 0, len(sort)+1)
+	for i, f := range sort {
+		sortColumns = append(sortColumns, columns[f.Field])
+		if f.Desc {
+			operators = append(operators, "<")
+		} else {
+			operators = append(operators, ">")
+		}
+		placeholders = append(placeholders, fmt.Sprintf("$%d", q.Placeholder(cursor.Values[i])))
+	}
+	sortColumns = append(sortColumns, idColumn)
+	operators = append(operators, ">")
+	placeholders = append(placeholders, fmt.Sprintf("$%d", q.Placeholder(cursor.ID)))
+
+	var branches []string
+	for i := range sortColumns {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = %s", sortColumns[j], placeholders[j]))
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", sortColumns[i], operators[i], placeholders[i]))
+		branches = append(branches, "("+strings.Join(parts, " AND ")+")")
+	}
+	q.conditions = append(q.conditions, "("+strings.Join(branches, " OR ")+")")
+}
+
+// OrderBy renders the ORDER BY clause, always ending with idColumn so the
+// order is total and cursors are stable.
+func OrderBy(sort []models.SortField, columns map[string]string, idColumn string) string {
+	parts := make([]string, 0, len(sort)+1)
+	for _, f := range sort {
+		direction := "ASC"
+		if f.Desc {
+			direction = "DESC"
+		}
+		parts = append(parts, columns[f.Field]+" "+direction)
+	}
+	parts = append(parts, idColumn+" ASC")
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// EscapeLike escapes LIKE wildcards; use it with ESCAPE '\'.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,103 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/google/uuid"
+)
+
+type TokenStore struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *TokenStore {
+	return &TokenStore{db: db}
+}
+
+func (t TokenStore) CreateRefreshToken(ctx context.Context, refreshToken models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, token_hash, username, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := t.db.ExecContext(ctx, query, uuid.New(), refreshToken.TokenHash, refreshToken.UserName, refreshToken.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+// ConsumeRefreshToken revokes a live refresh token and returns it, so each
+// refresh token can be exchanged exactly once.
+func (t TokenStore) ConsumeRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	var refreshToken models.RefreshToken
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $1
+		WHERE token_hash = $2 AND revoked_at IS NULL AND expires_at > $1
+		RETURNING token_hash, username, expires_at
+	`
+	err := t.db.QueryRowContext(ctx, query, time.Now(), tokenHash).Scan(
+		&refreshToken.TokenHash,
+		&refreshToken.UserName,
+		&refreshToken.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return refreshToken, models.ErrInvalidToken
+		}
+		return refreshToken, fmt.Errorf("failed to consume refresh token: %w", err)
+	}
+	return refreshToken, nil
+}
+
+func (t TokenStore) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := t.db.ExecContext(ctx,
+		"UPDATE refresh_tokens SET revoked_at = $1 WHERE token_hash = $2 AND revoked_at IS NULL",
+		time.Now(), tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+func (t TokenStore) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) (err error) {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING",
+		jti, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	// Entries are only needed until the token would have expired anyway
+	_, err = tx.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at < $1", time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to prune revoked tokens: %w", err)
+	}
+	return nil
+}
+
+func (t TokenStore) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := t.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return revoked, nil
+}
@@ -0,0 +1,155 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MarNawar/carZone/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type UserStore struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *UserStore {
+	return &UserStore{db: db}
+}
+
+const userColumns = `id, username, password_hash, role, disabled, locked, failed_login_attempts, created_at, updated_at`
+
+func scanUser(row *sql.Row) (models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.ID,
+		&user.UserName,
+		&user.PasswordHash,
+		&user.Role,
+		&user.Disabled,
+		&user.Locked,
+		&user.FailedLoginAttempts,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	return user, err
+}
+
+func (u UserStore) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1`
+
+	user, err := scanUser(u.db.QueryRowContext(ctx, query, username))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, fmt.Errorf("%w: %s", models.ErrUserNotFound, username)
+		}
+		return user, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	return user, nil
+}
+
+func (u UserStore) CreateUser(ctx context.Context, username string, passwordHash string, role models.Role) (models.User, error) {
+	currentTime := time.Now()
+	query := `
+		INSERT INTO users (id, username, password_hash, role, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + userColumns
+
+	user, err := scanUser(u.db.QueryRowContext(ctx, query, uuid.New(), username, passwordHash, role, currentTime, currentTime))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return user, fmt.Errorf("%w: %s", models.ErrUserExists, username)
+		}
+		return user, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+func (u UserStore) UpdatePassword(ctx context.Context, username string, passwordHash string) (models.User, error) {
+	query := `
+		UPDATE users
+		SET password_hash = $1, updated_at = $2
+		WHERE username = $3
+		RETURNING ` + userColumns
+
+	user, err := scanUser(u.db.QueryRowContext(ctx, query, passwordHash, time.Now(), username))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, fmt.Errorf("%w: %s", models.ErrUserNotFound, username)
+		}
+		return user, fmt.Errorf("failed to update password: %w", err)
+	}
+	return user, nil
+}
+
+func (u UserStore) UpdateRole(ctx context.Context, username string, role models.Role) (models.User, error) {
+	query := `
+		UPDATE users
+		SET role = $1, updated_at = $2
+		WHERE username = $3
+		RETURNING ` + userColumns
+
+	user, err := scanUser(u.db.QueryRowContext(ctx, query, role, time.Now(), username))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, fmt.Errorf("%w: %s", models.ErrUserNotFound, username)
+		}
+		return user, fmt.Errorf("failed to update role: %w", err)
+	}
+	return user, nil
+}
+
+// UpdateStatus sets the disabled and locked flags. Unlocking also clears
+// the failed login counter.
+func (u UserStore) UpdateStatus(ctx context.Context, username string, statusReq *models.UserStatusRequest) (models.User, error) {
+	query := `
+		UPDATE users
+		SET disabled = $1,
+			locked = $2,
+			failed_login_attempts = CASE WHEN $2 THEN failed_login_attempts ELSE 0 END,
+			updated_at = $3
+		WHERE username = $4
+		RETURNING ` + userColumns
+
+	user, err := scanUser(u.db.QueryRowContext(ctx, query, statusReq.Disabled, statusReq.Locked, time.Now(), username))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, fmt.Errorf("%w: %s", models.ErrUserNotFound, username)
+		}
+		return user, fmt.Errorf("failed to update user status: %w", err)
+	}
+	return user, nil
+}
+
+// RecordLoginFailure bumps the failed attempt counter and locks the account
+// once it reaches maxAttempts.
+func (u UserStore) RecordLoginFailure(ctx context.Context, username string, maxAttempts int) (models.User, error) {
+	query := `
+		UPDATE users
+		SET failed_login_attempts = failed_login_attempts + 1,
+			locked = locked OR failed_login_attempts + 1 >= $1,
+			updated_at = $2
+		WHERE username = $3
+		RETURNING ` + userColumns
+
+	user, err := scanUser(u.db.QueryRowContext(ctx, query, maxAttempts, time.Now(), username))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return user, fmt.Errorf("%w: %s", models.ErrUserNotFound, username)
+		}
+		return user, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return user, nil
+}
+
+func (u UserStore) ResetLoginFailures(ctx context.Context, username string) error {
+	_, err := u.db.ExecContext(ctx, "UPDATE users SET failed_login_attempts = 0 WHERE username = $1", username)
+	if err != nil {
+		return fmt.Errorf("failed to reset login failures: %w", err)
+	}
+	return nil
+}